@@ -0,0 +1,71 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hashicorp/vault-plugin-auth-tencentcloud/clients"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// apiConfig gathers the mount settings used to build TencentCloud API clients.
+type apiConfig struct {
+	endpoints  *endpointConfig
+	retry      *retryConfig
+	httpClient *http.Client
+}
+
+// readAPIConfig reads the settings used to build TencentCloud API clients.
+// The result is cached until a config entry changes.
+func (b *backend) readAPIConfig(ctx context.Context, s logical.Storage) (*apiConfig, error) {
+	gen := b.clients.generation()
+	if api := b.clients.api(); api != nil {
+		return api, nil
+	}
+	endpoints, err := readEndpointConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	retry, err := readRetryConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := b.getHTTPClient(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	api := &apiConfig{
+		endpoints:  endpoints,
+		retry:      retry,
+		httpClient: httpClient,
+	}
+	b.clients.setAPI(gen, api)
+	return api, nil
+}
+
+// stsConfig returns the client config for STS requests in the region.
+func (c *apiConfig) stsConfig(region string) *clients.Config {
+	if region == "" {
+		region = c.endpoints.DefaultRegion
+	}
+	return &clients.Config{
+		Region:     region,
+		Domain:     c.endpoints.Domain,
+		Endpoint:   c.endpoints.STSEndpoint,
+		Internal:   c.endpoints.UsePrivateEndpoint,
+		HTTPClient: c.httpClient,
+		Retry:      c.retry.policy(),
+	}
+}
+
+// camConfig returns the client config for CAM requests.
+func (c *apiConfig) camConfig() *clients.Config {
+	return &clients.Config{
+		Region:     c.endpoints.DefaultRegion,
+		Domain:     c.endpoints.Domain,
+		Endpoint:   c.endpoints.CAMEndpoint,
+		Internal:   c.endpoints.UsePrivateEndpoint,
+		HTTPClient: c.httpClient,
+		Retry:      c.retry.policy(),
+	}
+}
@@ -108,6 +108,12 @@ func parseARN(a string) (*arn, error) {
 	return parsed, nil
 }
 
+// roleARN returns the arn of the CAM role, qcs::cam::uin/<uin>:roleName/<RoleName>,
+// once RoleName is known.
+func (a *arn) roleARN() string {
+	return "qcs::cam::uin/" + a.Uin + ":" + roleName + "/" + a.RoleName
+}
+
 // toString
 func (a *arn) String() string {
 	return a.Full
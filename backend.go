@@ -3,15 +3,19 @@ package vault_plugin_auth_tencentcloud
 import (
 	"context"
 	"net/http"
+	"strings"
+	"sync"
 
 	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault-plugin-auth-tencentcloud/clients"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
 // Factory
 func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
-	client := cleanhttp.DefaultClient()
+	client := cleanhttp.DefaultPooledClient()
 	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 		return http.ErrUseLastResponse
 	}
@@ -22,24 +26,67 @@ func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend,
 	return b, nil
 }
 
+// FactoryWithClients returns a factory for backends that build their
+// TencentCloud API clients with clientFactory instead of the SDK.
+func FactoryWithClients(clientFactory clients.Factory) logical.Factory {
+	return func(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+		b := newBackend(cleanhttp.DefaultPooledClient())
+		b.clientFactory = clientFactory
+		if err := b.Setup(ctx, conf); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+}
+
 func newBackend(client *http.Client) *backend {
 	b := &backend{
 		identityClient: client,
+		clientFactory:  clients.SDKFactory{},
+		clients:        newClientCache(),
+		roles:          newRoleCache(),
+		lockouts:       newLockoutIndex(),
+		loginRates:     newLoginRateLimiter(),
 	}
+	b.notifier = newNotifier(func() hclog.Logger { return b.Logger() })
 	b.Backend = &framework.Backend{
-		AuthRenew: b.pathLoginRenew,
-		Help:      backendHelp,
+		AuthRenew:      b.pathLoginRenew,
+		Invalidate:     b.invalidate,
+		Clean:          b.cleanup,
+		PeriodicFunc:   b.pruneLockouts,
+		InitializeFunc: b.initialize,
+		Help:           backendHelp,
 		PathsSpecial: &logical.Paths{
 			Unauthenticated: []string{
 				"login",
+				"login/roles",
 			},
 		},
 		Paths: []*framework.Path{
 			pathLogin(b),
+			pathLoginRoles(b),
 			pathListRole(b),
 			pathListRoles(b),
-			pathRole(b),
-			pathConfigClient(b),
+			b.notifyChanges(eventRoleChanged, pathRole(b)),
+			b.notifyChanges(eventRoleChanged, pathRoleDisable(b)),
+			b.notifyChanges(eventRoleChanged, pathRoleEnable(b)),
+			pathRoleByARN(b),
+			pathRoleVerify(b),
+			b.notifyChanges(eventConfigChanged, pathConfigAccount(b)),
+			pathListConfigAccounts(b),
+			pathLegacyConfigClient(b),
+			b.notifyChanges(eventConfigChanged, pathConfigEndpoint(b)),
+			b.notifyChanges(eventConfigChanged, pathConfigNetwork(b)),
+			b.notifyChanges(eventConfigChanged, pathConfigRetry(b)),
+			b.notifyChanges(eventConfigChanged, pathConfigCache(b)),
+			b.notifyChanges(eventConfigChanged, pathConfigLogging(b)),
+			b.notifyChanges(eventConfigChanged, pathConfigNotifications(b)),
+			pathListConfigNotifications(b),
+			pathNotificationsStatus(b),
+			b.notifyChanges(eventConfigChanged, pathConfigLockout(b)),
+			b.notifyChanges(eventConfigChanged, pathConfigGuard(b)),
+			pathLockout(b),
+			pathListLockouts(b),
 		},
 		BackendType: logical.TypeCredential,
 	}
@@ -49,6 +96,82 @@ func newBackend(client *http.Client) *backend {
 type backend struct {
 	*framework.Backend
 	identityClient *http.Client
+
+	// clientFactory builds the STS and CAM clients.
+	clientFactory clients.Factory
+
+	// httpClient is built from config/network and shared by all outbound calls.
+	httpClient     *http.Client
+	httpClientLock sync.RWMutex
+
+	// clients caches the mount settings and server-side API clients.
+	clients *clientCache
+
+	// accountsLock serializes changes to config/account, so that no two
+	// entries are written for the same account.
+	accountsLock sync.Mutex
+
+	// roleNames caches CAM role names by RoleId, sized from config/cache.
+	roleNames     *roleNameCache
+	roleNamesLock sync.RWMutex
+
+	// roles caches decoded role entries.
+	roles *roleCache
+
+	// logging is config/logging, read on first use.
+	logging     *loggingConfig
+	loggingLock sync.RWMutex
+
+	// webhooks are the config/notifications entries, read on first use.
+	webhooks     []*webhookConfig
+	webhooksLock sync.RWMutex
+
+	// notifier delivers events to the webhooks.
+	notifier *notifier
+
+	// lockoutConfig is config/lockout, read on first use.
+	lockoutConfig     *lockoutConfig
+	lockoutConfigLock sync.RWMutex
+	// lockouts indexes and locks the lockout entries.
+	lockouts *lockoutIndex
+
+	// loginRates counts successful logins for max_logins_per_minute.
+	loginRates *loginRateLimiter
+
+	// guard checks logins before any TencentCloud call. It is built from
+	// config/guard on first use.
+	guard     *guard
+	guardLock sync.RWMutex
+}
+
+// invalidate drops state derived from storage when the underlying key changes,
+// e.g. on performance standbys.
+func (b *backend) invalidate(ctx context.Context, key string) {
+	switch {
+	case key == configNetworkStoragePath:
+		b.resetHTTPClient()
+	case key == configCacheStoragePath:
+		b.resetRoleNameCache()
+	case key == configLoggingStoragePath:
+		b.resetLoggingConfig()
+	case key == configLockoutStoragePath:
+		b.resetLockoutConfig()
+	case key == configGuardStoragePath:
+		b.resetGuard()
+	case strings.HasPrefix(key, configNotificationsStoragePrefix):
+		b.resetWebhooks()
+	case strings.HasPrefix(key, "config/"):
+		b.clients.reset()
+	case strings.HasPrefix(key, lockoutPrefix):
+		b.lockouts.add(strings.TrimPrefix(key, lockoutPrefix))
+	case strings.HasPrefix(key, rolePath):
+		b.roles.remove(strings.TrimPrefix(key, rolePath))
+	}
+}
+
+// cleanup stops the background work of the backend when it is unmounted.
+func (b *backend) cleanup(ctx context.Context) {
+	b.notifier.shutdown()
 }
 
 const backendHelp = `
@@ -0,0 +1,109 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault-plugin-auth-tencentcloud/clients"
+)
+
+// credentialRefreshWindow is how long before they expire cached clients
+// holding temporary credentials are rebuilt.
+const credentialRefreshWindow = 5 * time.Minute
+
+// clientCache holds the mount settings and the server-side API clients shared
+// across logins. It is emptied whenever a config entry changes. Every reset
+// bumps the generation, so values computed from older config are dropped
+// instead of being cached.
+type clientCache struct {
+	lock       sync.RWMutex
+	gen        uint64
+	apiConfig  *apiConfig
+	accounts   map[string]string
+	camClients map[string]*cachedCAMClient
+}
+
+type cachedCAMClient struct {
+	client     clients.CAMAPI
+	expiration time.Time
+}
+
+func newClientCache() *clientCache {
+	return &clientCache{
+		accounts:   make(map[string]string),
+		camClients: make(map[string]*cachedCAMClient),
+	}
+}
+
+// reset drops everything cached.
+func (c *clientCache) reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.gen++
+	c.apiConfig = nil
+	c.accounts = make(map[string]string)
+	c.camClients = make(map[string]*cachedCAMClient)
+}
+
+// generation must be read before the storage reads a cached value is built from.
+func (c *clientCache) generation() uint64 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.gen
+}
+
+func (c *clientCache) api() *apiConfig {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.apiConfig
+}
+
+func (c *clientCache) setAPI(gen uint64, api *apiConfig) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if gen == c.gen {
+		c.apiConfig = api
+	}
+}
+
+// account returns the name of the account config for uin, which is empty if
+// there is none, and whether the lookup was cached.
+func (c *clientCache) account(uin string) (string, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	name, ok := c.accounts[uin]
+	return name, ok
+}
+
+func (c *clientCache) setAccount(gen uint64, uin, name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if gen == c.gen {
+		c.accounts[uin] = name
+	}
+}
+
+// cam returns the cached client for key unless its credentials are about to expire.
+func (c *clientCache) cam(key string) clients.CAMAPI {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	cached, ok := c.camClients[key]
+	if !ok {
+		return nil
+	}
+	if !cached.expiration.IsZero() && time.Now().Add(credentialRefreshWindow).After(cached.expiration) {
+		return nil
+	}
+	return cached.client
+}
+
+func (c *clientCache) setCAM(gen uint64, key string, client clients.CAMAPI, expiration time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if gen == c.gen {
+		c.camClients[key] = &cachedCAMClient{
+			client:     client,
+			expiration: expiration,
+		}
+	}
+}
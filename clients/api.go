@@ -0,0 +1,46 @@
+package clients
+
+import (
+	"context"
+	"time"
+
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+)
+
+// STSAPI is the part of the STS API used by the backend.
+type STSAPI interface {
+	// GetCallerIdentity returns the identity of the client's credentials.
+	GetCallerIdentity(ctx context.Context) (*CallerIdentityRsp, error)
+	// AssumeRole returns temporary credentials of roleArn and when they expire.
+	AssumeRole(ctx context.Context, roleArn, sessionName string) (common.CredentialIface, time.Time, error)
+}
+
+// CAMAPI is the part of the CAM API used by the backend.
+type CAMAPI interface {
+	// GetRoleName returns the name of the role with the given id.
+	GetRoleName(ctx context.Context, roleId string) (string, error)
+	// GetRoleTags returns the tags of the role with the given id.
+	GetRoleTags(ctx context.Context, roleId string) (map[string]string, error)
+}
+
+// Factory builds API clients. Tests and embedders may replace the SDK
+// clients with their own implementations.
+type Factory interface {
+	NewSTSClient(creds common.CredentialIface, config *Config) (STSAPI, error)
+	NewCAMClient(creds common.CredentialIface, config *Config) (CAMAPI, error)
+}
+
+// SDKFactory builds clients backed by the TencentCloud SDK.
+type SDKFactory struct{}
+
+var _ Factory = SDKFactory{}
+
+// NewSTSClient
+func (SDKFactory) NewSTSClient(creds common.CredentialIface, config *Config) (STSAPI, error) {
+	return NewSTSClientWithCreds(creds, config)
+}
+
+// NewCAMClient
+func (SDKFactory) NewCAMClient(creds common.CredentialIface, config *Config) (CAMAPI, error) {
+	return NewCAMClientWithCreds(creds, config)
+}
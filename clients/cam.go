@@ -1,40 +1,101 @@
 package clients
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	cam "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cam/v20190116"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/regions"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	tcerr "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/errors"
 )
 
 // init New CAM Client
-func NewCAMClient(secretId, secretKey, token string) (*CAMClient, error) {
+func NewCAMClient(secretId, secretKey, token string, config *Config) (*CAMClient, error) {
 	creds, err := ChainedCredsToCli(secretId, secretKey, token)
 	if err != nil {
 		return nil, err
 	}
-	profile := profile.NewClientProfile()
-	profile.Language = "en-US"
-	profile.HttpProfile.ReqTimeout = 90
-	client, err := cam.NewClient(creds, regions.Ashburn, profile)
+	return NewCAMClientWithCreds(creds, config)
+}
+
+// NewCAMClientWithCreds init New CAM Client from already resolved credentials
+func NewCAMClientWithCreds(creds common.CredentialIface, config *Config) (*CAMClient, error) {
+	client, err := cam.NewClient(creds, config.region(), config.profile())
 	if err != nil {
 		return nil, err
 	}
-	return &CAMClient{client: client}, nil
+	config.apply(&client.Client)
+	return &CAMClient{client: client, region: config.region(), retry: config.retryPolicy()}, nil
 }
 
 // CAM Client
 type CAMClient struct {
 	client *cam.Client
+	region string
+	retry  *RetryPolicy
 }
 
 // API： GetRoleName
-func (c *CAMClient) GetRoleName(roleId string) (roleName string, err error) {
+func (c *CAMClient) GetRoleName(ctx context.Context, roleId string) (roleName string, err error) {
+	info, err := c.getRole(ctx, roleId)
+	if err != nil {
+		return "", err
+	}
+	if info.RoleName == nil {
+		return "", fmt.Errorf("no role info returned for role id %s", roleId)
+	}
+	return *info.RoleName, nil
+}
+
+// API： GetRoleTags
+func (c *CAMClient) GetRoleTags(ctx context.Context, roleId string) (map[string]string, error) {
+	info, err := c.getRole(ctx, roleId)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string, len(info.Tags))
+	for _, tag := range info.Tags {
+		if tag == nil || tag.Key == nil {
+			continue
+		}
+		value := ""
+		if tag.Value != nil {
+			value = *tag.Value
+		}
+		tags[*tag.Key] = value
+	}
+	return tags, nil
+}
+
+// getRole calls GetRole for the role with the given id.
+func (c *CAMClient) getRole(ctx context.Context, roleId string) (*cam.RoleInfo, error) {
 	req := cam.NewGetRoleRequest()
 	req.RoleId = &roleId
-	roleRsp, err := c.client.GetRole(req)
+	var roleRsp *cam.GetRoleResponse
+	err := c.retry.do(ctx, func() (err error) {
+		start := time.Now()
+		roleRsp, err = c.client.GetRoleWithContext(ctx, req)
+		measureCall(camService, "GetRole", c.region, start, err)
+		return err
+	})
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if roleRsp.Response == nil || roleRsp.Response.RoleInfo == nil {
+		return nil, fmt.Errorf("no role info returned for role id %s", roleId)
 	}
-	return *(roleRsp.Response.RoleInfo.RoleName), nil
+	return roleRsp.Response.RoleInfo, nil
+}
 
+// IsRoleNotFound reports whether err is CAM's answer for a role that does not exist.
+func IsRoleNotFound(err error) bool {
+	var sdkErr *tcerr.TencentCloudSDKError
+	if !errors.As(err, &sdkErr) {
+		return false
+	}
+	code := sdkErr.GetCode()
+	return code == cam.INVALIDPARAMETER_ROLENOTEXIST || strings.HasPrefix(code, "ResourceNotFound")
 }
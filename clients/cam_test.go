@@ -1,6 +1,7 @@
 package clients
 
 import (
+	"context"
 	"fmt"
 	cam "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cam/v20190116"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
@@ -52,7 +53,7 @@ func TestCAMClient_GetRoleName(t *testing.T) {
 			c := &CAMClient{
 				client: tt.fields.client,
 			}
-			gotRoleName, err := c.GetRoleName(tt.args.roleId)
+			gotRoleName, err := c.GetRoleName(context.Background(), tt.args.roleId)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetRoleName() error = %v, wantErr %v", err, tt.wantErr)
 				return
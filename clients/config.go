@@ -0,0 +1,108 @@
+package clients
+
+import (
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/regions"
+)
+
+const (
+	// DefaultDomain is the API domain of the China site.
+	DefaultDomain = "tencentcloudapi.com"
+	// DefaultRegion is used when neither the caller nor the mount picks a region.
+	DefaultRegion = regions.Ashburn
+
+	internalSubdomain = "internal."
+)
+
+// Config holds the endpoint settings used to build an STS or CAM client.
+type Config struct {
+	// Region is the region requests are made in.
+	Region string
+	// Domain is the API root domain, e.g. tencentcloudapi.com or intl.tencentcloudapi.com.
+	Domain string
+	// Endpoint overrides the service endpoint. It may be a host or a URL with a scheme.
+	Endpoint string
+	// Internal selects the private-network endpoint of the service.
+	Internal bool
+	// HTTPClient supplies the transport and request timeout. The SDK defaults are used if nil.
+	HTTPClient *http.Client
+	// Retry controls retries of failed calls. DefaultRetryPolicy is used if nil.
+	Retry *RetryPolicy
+}
+
+// region returns the configured region or DefaultRegion.
+func (c *Config) region() string {
+	if c == nil || c.Region == "" {
+		return DefaultRegion
+	}
+	return c.Region
+}
+
+// profile builds the SDK client profile for the config.
+func (c *Config) profile() *profile.ClientProfile {
+	profile := profile.NewClientProfile()
+	profile.Language = "en-US"
+	profile.HttpProfile.ReqTimeout = 90
+	if c == nil {
+		return profile
+	}
+	if c.HTTPClient != nil && c.HTTPClient.Timeout > 0 {
+		profile.HttpProfile.ReqTimeout = int(math.Ceil(c.HTTPClient.Timeout.Seconds()))
+	}
+	domain := c.Domain
+	if c.Internal {
+		if domain == "" {
+			domain = DefaultDomain
+		}
+		if !strings.HasPrefix(domain, internalSubdomain) {
+			domain = internalSubdomain + domain
+		}
+	}
+	profile.HttpProfile.RootDomain = domain
+	if c.Endpoint != "" {
+		scheme, host := splitEndpoint(c.Endpoint)
+		if scheme != "" {
+			profile.HttpProfile.Scheme = scheme
+		}
+		profile.HttpProfile.Endpoint = host
+	}
+	return profile
+}
+
+// splitEndpoint separates an optional scheme from an endpoint host.
+func splitEndpoint(endpoint string) (scheme, host string) {
+	if !strings.Contains(endpoint, "://") {
+		return "", endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "", endpoint
+	}
+	return strings.ToUpper(u.Scheme), u.Host
+}
+
+// retryPolicy returns the configured retry policy or the default one.
+func (c *Config) retryPolicy() *RetryPolicy {
+	if c == nil || c.Retry == nil {
+		return DefaultRetryPolicy()
+	}
+	return c.Retry
+}
+
+// apply points an SDK client at the configured HTTP client's transport.
+func (c *Config) apply(client *common.Client) {
+	if c == nil || c.HTTPClient == nil {
+		return
+	}
+	transport := c.HTTPClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	client.WithHttpTransport(transport)
+}
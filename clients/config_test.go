@@ -0,0 +1,75 @@
+package clients
+
+import (
+	"testing"
+)
+
+func TestConfig_Profile(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       *Config
+		wantRegion   string
+		wantDomain   string
+		wantEndpoint string
+		wantScheme   string
+	}{
+		{
+			name:       "nil config",
+			config:     nil,
+			wantRegion: DefaultRegion,
+			wantScheme: "HTTPS",
+		},
+		{
+			name:       "international site",
+			config:     &Config{Region: "ap-singapore", Domain: "intl.tencentcloudapi.com"},
+			wantRegion: "ap-singapore",
+			wantDomain: "intl.tencentcloudapi.com",
+			wantScheme: "HTTPS",
+		},
+		{
+			name:       "private network",
+			config:     &Config{Region: "ap-shanghai", Internal: true},
+			wantRegion: "ap-shanghai",
+			wantDomain: "internal.tencentcloudapi.com",
+			wantScheme: "HTTPS",
+		},
+		{
+			name:       "private network with explicit internal domain",
+			config:     &Config{Domain: "internal.tencentcloudapi.com", Internal: true},
+			wantRegion: DefaultRegion,
+			wantDomain: "internal.tencentcloudapi.com",
+			wantScheme: "HTTPS",
+		},
+		{
+			name:         "endpoint host",
+			config:       &Config{Endpoint: "sts.ap-shanghai.tencentcloudapi.com"},
+			wantRegion:   DefaultRegion,
+			wantEndpoint: "sts.ap-shanghai.tencentcloudapi.com",
+			wantScheme:   "HTTPS",
+		},
+		{
+			name:         "endpoint url",
+			config:       &Config{Endpoint: "http://127.0.0.1:8080"},
+			wantRegion:   DefaultRegion,
+			wantEndpoint: "127.0.0.1:8080",
+			wantScheme:   "HTTP",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.region(); got != tt.wantRegion {
+				t.Errorf("region() = %v, want %v", got, tt.wantRegion)
+			}
+			p := tt.config.profile()
+			if p.HttpProfile.RootDomain != tt.wantDomain {
+				t.Errorf("RootDomain = %v, want %v", p.HttpProfile.RootDomain, tt.wantDomain)
+			}
+			if p.HttpProfile.Endpoint != tt.wantEndpoint {
+				t.Errorf("Endpoint = %v, want %v", p.HttpProfile.Endpoint, tt.wantEndpoint)
+			}
+			if p.HttpProfile.Scheme != tt.wantScheme {
+				t.Errorf("Scheme = %v, want %v", p.HttpProfile.Scheme, tt.wantScheme)
+			}
+		})
+	}
+}
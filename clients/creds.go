@@ -1,9 +1,14 @@
 package clients
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	tcerr "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/errors"
 )
 
 // client chainedCreds for Cli
@@ -16,6 +21,17 @@ func ChainedCredsToCli(secretId, secretKey, token string) (common.CredentialIfac
 	return common.NewProviderChain(providerChain).GetCredential()
 }
 
+// AssumeRoleCreds exchanges the given credentials for temporary credentials of roleArn.
+// It also returns when the temporary credentials expire.
+func AssumeRoleCreds(ctx context.Context, creds common.CredentialIface, roleArn, sessionName string,
+	config *Config) (common.CredentialIface, time.Time, error) {
+	client, err := NewSTSClientWithCreds(creds, config)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return client.AssumeRole(ctx, roleArn, sessionName)
+}
+
 // Configuration
 type Configuration struct {
 	SecretId  string
@@ -47,3 +63,15 @@ func (p *ConfigurationProvider) GetCredential() (common.CredentialIface, error)
 var (
 	ErrNoValidCredentialsFound = fmt.Errorf("no valid credentials were found")
 )
+
+// IsAuthFailure reports whether err is a rejection of the credentials a call
+// was signed with, or of their permission to make it.
+func IsAuthFailure(err error) bool {
+	var sdkErr *tcerr.TencentCloudSDKError
+	if !errors.As(err, &sdkErr) {
+		return false
+	}
+	code := sdkErr.GetCode()
+	return strings.HasPrefix(code, "AuthFailure") || strings.HasPrefix(code, "UnauthorizedOperation") ||
+		code == "InvalidParameter.AccessKeyNotSupport"
+}
@@ -0,0 +1,182 @@
+package fake
+
+import (
+	"sort"
+
+	cam "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cam/v20190116"
+)
+
+const defaultPageSize = 20
+
+// findRole returns the role with the given id, or else the given name.
+func (s *Server) findRole(roleId, roleName string) *Role {
+	if roleId != "" {
+		return s.roles[roleId]
+	}
+	ids := make([]string, 0, len(s.roles))
+	for id := range s.roles {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if s.roles[id].RoleName == roleName {
+			return s.roles[id]
+		}
+	}
+	return nil
+}
+
+// callerRole returns the role the request names, which must belong to the caller's account.
+func (s *Server) callerRole(identity *Identity, roleId, roleName *string) (*Role, *apiError) {
+	var id, name string
+	if roleId != nil {
+		id = *roleId
+	}
+	if roleName != nil {
+		name = *roleName
+	}
+	if id == "" && name == "" {
+		return nil, newAPIError("InvalidParameter.ParamError", "RoleId or RoleName is required")
+	}
+	role := s.findRole(id, name)
+	if role == nil || role.AccountId != identity.AccountId {
+		return nil, newAPIError(cam.INVALIDPARAMETER_ROLENOTEXIST, "role does not exist")
+	}
+	return role, nil
+}
+
+// getRole answers cam:GetRole.
+func (s *Server) getRole(identity *Identity, body []byte) (interface{}, *apiError) {
+	params := &cam.GetRoleRequestParams{}
+	if err := decode(body, params); err != nil {
+		return nil, err
+	}
+	role, err := s.callerRole(identity, params.RoleId, params.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	roleArn := "qcs::cam::uin/" + role.AccountId + ":roleName/" + role.RoleName
+	info := &cam.RoleInfo{
+		RoleId:         strPtr(role.RoleId),
+		RoleName:       strPtr(role.RoleName),
+		PolicyDocument: strPtr(role.PolicyDocument),
+		Description:    strPtr(role.Description),
+		RoleType:       strPtr("user"),
+		RoleArn:        &roleArn,
+	}
+	for _, tag := range role.Tags {
+		info.Tags = append(info.Tags, &cam.RoleTags{
+			Key:   strPtr(tag.Key),
+			Value: strPtr(tag.Value),
+		})
+	}
+	return &cam.GetRoleResponseParams{RoleInfo: info}, nil
+}
+
+// listAttachedRolePolicies answers cam:ListAttachedRolePolicies.
+func (s *Server) listAttachedRolePolicies(identity *Identity, body []byte) (interface{}, *apiError) {
+	params := &cam.ListAttachedRolePoliciesRequestParams{}
+	if err := decode(body, params); err != nil {
+		return nil, err
+	}
+	role, err := s.callerRole(identity, params.RoleId, params.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	var policies []*cam.AttachedPolicyOfRole
+	for _, policy := range role.Policies {
+		if params.PolicyType != nil && *params.PolicyType != "" && *params.PolicyType != policy.PolicyType {
+			continue
+		}
+		policies = append(policies, &cam.AttachedPolicyOfRole{
+			PolicyId:   uint64Ptr(policy.PolicyId),
+			PolicyName: strPtr(policy.PolicyName),
+			PolicyType: strPtr(policy.PolicyType),
+		})
+	}
+	total := uint64(len(policies))
+	page, size := uint64(1), uint64(defaultPageSize)
+	if params.Page != nil && *params.Page > 0 {
+		page = *params.Page
+	}
+	if params.Rp != nil && *params.Rp > 0 {
+		size = *params.Rp
+	}
+	start := (page - 1) * size
+	if start > total {
+		start = total
+	}
+	end := start + size
+	if end > total {
+		end = total
+	}
+	return &cam.ListAttachedRolePoliciesResponseParams{
+		List:     policies[start:end],
+		TotalNum: &total,
+	}, nil
+}
+
+// tagRole answers cam:TagRole, adding tags and replacing the values of existing keys.
+func (s *Server) tagRole(identity *Identity, body []byte) (interface{}, *apiError) {
+	params := &cam.TagRoleRequestParams{}
+	if err := decode(body, params); err != nil {
+		return nil, err
+	}
+	role, err := s.callerRole(identity, params.RoleId, params.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range params.Tags {
+		if tag == nil || tag.Key == nil || *tag.Key == "" {
+			return nil, newAPIError("InvalidParameter.ParamError", "tag keys must not be empty")
+		}
+		value := ""
+		if tag.Value != nil {
+			value = *tag.Value
+		}
+		replaced := false
+		for i := range role.Tags {
+			if role.Tags[i].Key == *tag.Key {
+				role.Tags[i].Value, replaced = value, true
+			}
+		}
+		if !replaced {
+			role.Tags = append(role.Tags, Tag{Key: *tag.Key, Value: value})
+		}
+	}
+	return &cam.TagRoleResponseParams{}, nil
+}
+
+// untagRole answers cam:UntagRole.
+func (s *Server) untagRole(identity *Identity, body []byte) (interface{}, *apiError) {
+	params := &cam.UntagRoleRequestParams{}
+	if err := decode(body, params); err != nil {
+		return nil, err
+	}
+	role, err := s.callerRole(identity, params.RoleId, params.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	remove := make(map[string]bool)
+	for _, key := range params.TagKeys {
+		if key != nil {
+			remove[*key] = true
+		}
+	}
+	tags := role.Tags[:0]
+	for _, tag := range role.Tags {
+		if !remove[tag.Key] {
+			tags = append(tags, tag)
+		}
+	}
+	role.Tags = tags
+	return &cam.UntagRoleResponseParams{}, nil
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func uint64Ptr(u uint64) *uint64 {
+	return &u
+}
@@ -0,0 +1,348 @@
+// Package fake emulates the TencentCloud STS and CAM APIs used by the backend,
+// so logins can be tested against realistic identities without the live cloud.
+//
+// A Server answers the STS GetCallerIdentity and AssumeRole actions and the CAM
+// GetRole, ListAttachedRolePolicies, TagRole and UntagRole actions. Requests
+// must carry a valid TC3-HMAC-SHA256 signature made with the secret key of a
+// known identity. Point the backend's sts_endpoint and cam_endpoint at the
+// server's URL to use it.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+)
+
+const (
+	stsService = "sts"
+	camService = "cam"
+
+	// maxBodySize bounds the request bodies the server reads.
+	maxBodySize = 1 << 20
+	// maxClockSkew is how far a request timestamp may be from the server's clock.
+	maxClockSkew = 5 * time.Minute
+	// defaultSessionDuration is how long AssumeRole credentials last by default.
+	defaultSessionDuration = 2 * time.Hour
+	// maxSessionDuration is the longest AssumeRole session allowed.
+	maxSessionDuration = 12 * time.Hour
+)
+
+// Identity is a set of credentials and the identity STS reports for them.
+type Identity struct {
+	SecretId  string `json:"secret_id"`
+	SecretKey string `json:"secret_key"`
+	// Token, if set, must be sent along with the secret id.
+	Token string `json:"token"`
+
+	// Type is CAMRole, CAMUser or RootAccount.
+	Type        string `json:"type"`
+	Arn         string `json:"arn"`
+	AccountId   string `json:"account_id"`
+	UserId      string `json:"user_id"`
+	PrincipalId string `json:"principal_id"`
+
+	// Expiration, if set, is when the credentials stop working.
+	Expiration time.Time `json:"expiration"`
+}
+
+// Role is a CAM role.
+type Role struct {
+	RoleId         string   `json:"role_id"`
+	RoleName       string   `json:"role_name"`
+	AccountId      string   `json:"account_id"`
+	Description    string   `json:"description"`
+	PolicyDocument string   `json:"policy_document"`
+	Tags           []Tag    `json:"tags"`
+	Policies       []Policy `json:"policies"`
+	// TrustedAccounts lists the accounts whose identities may assume the
+	// role. If empty, any identity may assume it.
+	TrustedAccounts []string `json:"trusted_accounts"`
+}
+
+// Tag is a CAM role tag.
+type Tag struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Policy is a policy attached to a CAM role.
+type Policy struct {
+	PolicyId   uint64 `json:"policy_id"`
+	PolicyName string `json:"policy_name"`
+	// PolicyType is User for custom policies and QCS for preset ones.
+	PolicyType string `json:"policy_type"`
+}
+
+// Fault makes matching requests fail instead of being answered.
+type Fault struct {
+	// Service, Action and Region restrict which requests fail. Empty matches any.
+	Service string `json:"service"`
+	Action  string `json:"action"`
+	Region  string `json:"region"`
+
+	// Code and Message make the request fail with an API error.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Status, if set, makes the request fail with this HTTP status and a
+	// body that is not an API response, like a gateway in front of the API.
+	Status int `json:"status"`
+
+	// Count is the number of requests that fail. 0 fails every request.
+	Count int `json:"count"`
+}
+
+// Scenario is the set of identities, roles and faults a server starts with.
+type Scenario struct {
+	Identities []*Identity `json:"identities"`
+	Roles      []*Role     `json:"roles"`
+	Faults     []*Fault    `json:"faults"`
+}
+
+// Call records a request the server answered.
+type Call struct {
+	Service  string
+	Action   string
+	Region   string
+	SecretId string
+	// Code is the error code returned, empty if the request succeeded.
+	Code string
+}
+
+// Server emulates the STS and CAM APIs. It is safe for concurrent use.
+type Server struct {
+	lock       sync.Mutex
+	identities map[string]*Identity
+	roles      map[string]*Role
+	faults     []*Fault
+	calls      []Call
+
+	// now returns the server's clock, used to check request timestamps and
+	// credential expiration.
+	now func() time.Time
+}
+
+// New returns a server with no identities or roles.
+func New() *Server {
+	return &Server{
+		identities: make(map[string]*Identity),
+		roles:      make(map[string]*Role),
+		now:        time.Now,
+	}
+}
+
+// NewFromScenario returns a server loaded with the scenario.
+func NewFromScenario(scenario *Scenario) *Server {
+	s := New()
+	for _, identity := range scenario.Identities {
+		s.AddIdentity(identity)
+	}
+	for _, role := range scenario.Roles {
+		s.AddRole(role)
+	}
+	for _, fault := range scenario.Faults {
+		s.AddFault(fault)
+	}
+	return s
+}
+
+// AddIdentity registers credentials, replacing any with the same secret id.
+func (s *Server) AddIdentity(identity *Identity) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	copied := *identity
+	s.identities[identity.SecretId] = &copied
+}
+
+// RemoveIdentity makes the credentials unknown, as if they had been deleted.
+func (s *Server) RemoveIdentity(secretId string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.identities, secretId)
+}
+
+// AddRole registers a role, replacing any with the same id.
+func (s *Server) AddRole(role *Role) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	copied := *role
+	copied.Tags = append([]Tag(nil), role.Tags...)
+	copied.Policies = append([]Policy(nil), role.Policies...)
+	s.roles[role.RoleId] = &copied
+}
+
+// RemoveRole deletes the role with the given id.
+func (s *Server) RemoveRole(roleId string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.roles, roleId)
+}
+
+// AddFault injects a fault. Faults are matched in the order they were added.
+func (s *Server) AddFault(fault *Fault) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	copied := *fault
+	s.faults = append(s.faults, &copied)
+}
+
+// ClearFaults removes all injected faults.
+func (s *Server) ClearFaults() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults = nil
+}
+
+// Calls returns the requests answered so far, oldest first.
+func (s *Server) Calls() []Call {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// apiError is an error returned in the body of an API response.
+type apiError struct {
+	Code    string
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+func newAPIError(code, format string, args ...interface{}) *apiError {
+	return &apiError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// ServeHTTP answers a TencentCloud API request.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	requestId, err := uuid.GenerateUUID()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	call := Call{
+		Action: header(r, "X-TC-Action"),
+		Region: header(r, "X-TC-Region"),
+	}
+	sig, apiErr := parseAuthorization(r)
+	if apiErr == nil {
+		call.Service, call.SecretId = sig.service, sig.secretId
+		if fault := s.matchFault(call); fault != nil {
+			if fault.Status != 0 {
+				call.Code = fmt.Sprintf("HTTP %d", fault.Status)
+				s.calls = append(s.calls, call)
+				w.Header().Set("Content-Type", "text/html")
+				w.WriteHeader(fault.Status)
+				fmt.Fprintf(w, "<html><body>%d %s</body></html>", fault.Status, http.StatusText(fault.Status))
+				return
+			}
+			apiErr = &apiError{Code: fault.Code, Message: fault.Message}
+		}
+	}
+	var identity *Identity
+	if apiErr == nil {
+		identity, apiErr = s.authenticate(r, sig, body)
+	}
+	var rsp interface{}
+	if apiErr == nil {
+		rsp, apiErr = s.dispatch(call, identity, body)
+	}
+	if apiErr != nil {
+		call.Code = apiErr.Code
+		rsp = map[string]interface{}{
+			"Error": apiErr,
+		}
+	}
+	s.calls = append(s.calls, call)
+	writeResponse(w, requestId, rsp)
+}
+
+// matchFault returns the first fault matching the call and uses it up.
+func (s *Server) matchFault(call Call) *Fault {
+	for i, fault := range s.faults {
+		if fault.Service != "" && fault.Service != call.Service ||
+			fault.Action != "" && fault.Action != call.Action ||
+			fault.Region != "" && fault.Region != call.Region {
+			continue
+		}
+		if fault.Count > 0 {
+			if fault.Count--; fault.Count == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return fault
+	}
+	return nil
+}
+
+// dispatch runs the action for an authenticated caller.
+func (s *Server) dispatch(call Call, identity *Identity, body []byte) (interface{}, *apiError) {
+	switch call.Service + "/" + call.Action {
+	case stsService + "/GetCallerIdentity":
+		return s.getCallerIdentity(identity)
+	case stsService + "/AssumeRole":
+		return s.assumeRole(identity, body)
+	case camService + "/GetRole":
+		return s.getRole(identity, body)
+	case camService + "/ListAttachedRolePolicies":
+		return s.listAttachedRolePolicies(identity, body)
+	case camService + "/TagRole":
+		return s.tagRole(identity, body)
+	case camService + "/UntagRole":
+		return s.untagRole(identity, body)
+	}
+	return nil, newAPIError("InvalidAction", "action %s of service %s is not supported", call.Action, call.Service)
+}
+
+// writeResponse wraps rsp, a response params struct or an error, the way the API does.
+func writeResponse(w http.ResponseWriter, requestId string, rsp interface{}) {
+	raw, err := json.Marshal(rsp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fields["RequestId"], _ = json.Marshal(requestId)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"Response": fields,
+	})
+}
+
+// header reads a header the way the SDK sets it, without canonicalizing its name.
+func header(r *http.Request, name string) string {
+	if values := r.Header[name]; len(values) > 0 {
+		return values[0]
+	}
+	return r.Header.Get(name)
+}
+
+// decode unmarshals the request body into params.
+func decode(body []byte, params interface{}) *apiError {
+	if len(body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, params); err != nil {
+		return newAPIError("InvalidParameter", "malformed request body: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault-plugin-auth-tencentcloud/clients"
+	cam "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cam/v20190116"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	tcerr "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/errors"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+)
+
+func testServer(t *testing.T) (*Server, *clients.Config) {
+	s := NewFromScenario(&Scenario{
+		Identities: []*Identity{
+			{
+				SecretId:    "AKIDdeployer",
+				SecretKey:   "deployerSecretKey",
+				Type:        "CAMUser",
+				Arn:         "qcs::cam::uin/1000262888:uin/1000262999",
+				AccountId:   "1000262888",
+				UserId:      "1000262999",
+				PrincipalId: "1000262999",
+			},
+		},
+		Roles: []*Role{
+			{
+				RoleId:    "4611686018427418890",
+				RoleName:  "elk",
+				AccountId: "1000262888",
+				Tags:      []Tag{{Key: "team", Value: "search"}},
+				Policies: []Policy{
+					{PolicyId: 1, PolicyName: "QcloudCOSReadOnlyAccess", PolicyType: "QCS"},
+					{PolicyId: 2, PolicyName: "elk-logs", PolicyType: "User"},
+				},
+			},
+		},
+	})
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	return s, &clients.Config{
+		Region:   "ap-guangzhou",
+		Endpoint: server.URL,
+		Retry:    &clients.RetryPolicy{},
+	}
+}
+
+func sdkCode(err error) string {
+	var sdkErr *tcerr.TencentCloudSDKError
+	if errors.As(err, &sdkErr) {
+		return sdkErr.GetCode()
+	}
+	return ""
+}
+
+func TestServer_AssumeRoleAndLookup(t *testing.T) {
+	ctx := context.Background()
+	s, config := testServer(t)
+
+	user, err := clients.NewSTSClientWithCreds(common.NewCredential("AKIDdeployer", "deployerSecretKey"), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := user.GetCallerIdentity(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Type != "CAMUser" || identity.UserId != "1000262999" {
+		t.Fatalf("unexpected identity: %#v", identity)
+	}
+
+	creds, expiration, err := user.AssumeRole(ctx, "qcs::cam::uin/1000262888:roleName/elk", "deploy-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if until := time.Until(expiration); until < time.Hour || until > defaultSessionDuration {
+		t.Fatalf("unexpected expiration %s", expiration)
+	}
+	role, err := clients.NewSTSClientWithCreds(creds, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity, err = role.GetCallerIdentity(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if identity.Type != "CAMRole" || identity.Arn != "qcs::sts:1000262888:assumed-role/4611686018427418890" ||
+		identity.UserId != "4611686018427418890:deploy-1" {
+		t.Fatalf("unexpected identity: %#v", identity)
+	}
+
+	camClient, err := clients.NewCAMClientWithCreds(creds, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, err := camClient.GetRoleName(ctx, "4611686018427418890")
+	if err != nil || name != "elk" {
+		t.Fatalf("bad: name: %q err: %v", name, err)
+	}
+	if _, err := camClient.GetRoleName(ctx, "4611686018427410000"); !clients.IsRoleNotFound(err) {
+		t.Fatalf("expected a missing role, got %v", err)
+	}
+
+	// The tag and policy APIs are answered too.
+	prof := profile.NewClientProfile()
+	prof.HttpProfile.Scheme = "HTTP"
+	prof.HttpProfile.Endpoint = strings.TrimPrefix(config.Endpoint, "http://")
+	sdkClient, err := cam.NewClient(creds, config.Region, prof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagReq := cam.NewTagRoleRequest()
+	tagReq.RoleName = strPtr("elk")
+	tagReq.Tags = []*cam.RoleTags{{Key: strPtr("env"), Value: strPtr("prod")}}
+	if _, err := sdkClient.TagRoleWithContext(ctx, tagReq); err != nil {
+		t.Fatal(err)
+	}
+	getReq := cam.NewGetRoleRequest()
+	getReq.RoleId = strPtr("4611686018427418890")
+	getRsp, err := sdkClient.GetRoleWithContext(ctx, getReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags := getRsp.Response.RoleInfo.Tags; len(tags) != 2 || *tags[1].Key != "env" {
+		t.Fatalf("unexpected tags: %v", tags)
+	}
+	listReq := cam.NewListAttachedRolePoliciesRequest()
+	listReq.RoleId = strPtr("4611686018427418890")
+	listReq.PolicyType = strPtr("User")
+	listRsp, err := sdkClient.ListAttachedRolePoliciesWithContext(ctx, listReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *listRsp.Response.TotalNum != 1 || *listRsp.Response.List[0].PolicyName != "elk-logs" {
+		t.Fatalf("unexpected policies: %v", listRsp.ToJsonString())
+	}
+
+	if calls := s.Calls(); len(calls) != 8 || calls[2].Action != "GetCallerIdentity" || calls[4].Code == "" {
+		t.Fatalf("unexpected calls: %#v", calls)
+	}
+}
+
+func TestServer_Authentication(t *testing.T) {
+	ctx := context.Background()
+	s, config := testServer(t)
+
+	for _, tc := range []struct {
+		name  string
+		creds common.CredentialIface
+		code  string
+	}{
+		{"unknown secret id", common.NewCredential("AKIDunknown", "deployerSecretKey"), "AuthFailure.SecretIdNotFound"},
+		{"wrong secret key", common.NewCredential("AKIDdeployer", "wrongSecretKey"), "AuthFailure.SignatureFailure"},
+		{"unexpected token", common.NewTokenCredential("AKIDdeployer", "deployerSecretKey", "token"), "AuthFailure.TokenFailure"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client, err := clients.NewSTSClientWithCreds(tc.creds, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := client.GetCallerIdentity(ctx); sdkCode(err) != tc.code {
+				t.Fatalf("expected %s, got %v", tc.code, err)
+			}
+		})
+	}
+
+	// Temporary credentials stop working once they expire.
+	user, err := clients.NewSTSClientWithCreds(common.NewCredential("AKIDdeployer", "deployerSecretKey"), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds, _, err := user.AssumeRole(ctx, "qcs::cam::uin/1000262888:roleName/elk", "deploy-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	role, err := clients.NewSTSClientWithCreds(creds, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.lock.Lock()
+	s.identities[creds.GetSecretId()].Expiration = time.Now().Add(-time.Second)
+	s.lock.Unlock()
+	if _, err := role.GetCallerIdentity(ctx); sdkCode(err) != "AuthFailure.TokenFailure" {
+		t.Fatalf("expected the credentials to be expired, got %v", err)
+	}
+
+	// Requests signed too long ago are rejected.
+	s.lock.Lock()
+	s.now = func() time.Time { return time.Now().Add(10 * time.Minute) }
+	s.lock.Unlock()
+	if _, err := user.GetCallerIdentity(ctx); sdkCode(err) != "AuthFailure.SignatureExpire" {
+		t.Fatalf("expected the request to be too old, got %v", err)
+	}
+
+	// A request that is not signed is rejected.
+	rsp, err := http.Post(config.Endpoint, "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+	if calls := s.Calls(); calls[len(calls)-1].Code != "AuthFailure.SignatureFailure" {
+		t.Fatalf("unexpected calls: %#v", calls)
+	}
+}
+
+func TestServer_Faults(t *testing.T) {
+	ctx := context.Background()
+	s, config := testServer(t)
+	client, err := clients.NewSTSClientWithCreds(common.NewCredential("AKIDdeployer", "deployerSecretKey"), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.AddFault(&Fault{Action: "GetCallerIdentity", Code: "RequestLimitExceeded", Message: "slow down", Count: 1})
+	if _, err := client.GetCallerIdentity(ctx); sdkCode(err) != "RequestLimitExceeded" {
+		t.Fatalf("expected the injected error, got %v", err)
+	}
+	if _, err := client.GetCallerIdentity(ctx); err != nil {
+		t.Fatalf("expected the fault to be used up, got %v", err)
+	}
+
+	s.AddFault(&Fault{Region: "ap-guangzhou", Status: http.StatusBadGateway})
+	if _, err := client.GetCallerIdentity(ctx); !clients.IsRegionalFailure(err) {
+		t.Fatalf("expected a regional failure, got %v", err)
+	}
+	other := *config
+	other.Region = "ap-shanghai"
+	otherClient, err := clients.NewSTSClientWithCreds(common.NewCredential("AKIDdeployer", "deployerSecretKey"), &other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := otherClient.GetCallerIdentity(ctx); err != nil {
+		t.Fatalf("expected other regions to work, got %v", err)
+	}
+	s.ClearFaults()
+	if _, err := client.GetCallerIdentity(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,134 @@
+package fake
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const tc3Algorithm = "TC3-HMAC-SHA256"
+
+// signature is a parsed TC3-HMAC-SHA256 Authorization header.
+type signature struct {
+	secretId      string
+	date          string
+	service       string
+	signedHeaders string
+	signature     string
+}
+
+// parseAuthorization parses the Authorization header, e.g.
+// TC3-HMAC-SHA256 Credential=AKID/2019-02-25/cvm/tc3_request, SignedHeaders=content-type;host, Signature=...
+func parseAuthorization(r *http.Request) (*signature, *apiError) {
+	auth := header(r, "Authorization")
+	if auth == "" {
+		return nil, newAPIError("AuthFailure.SignatureFailure", "the request is not signed")
+	}
+	if !strings.HasPrefix(auth, tc3Algorithm+" ") {
+		return nil, newAPIError("AuthFailure.InvalidAuthorization", "only %s signatures are supported", tc3Algorithm)
+	}
+	sig := &signature{}
+	for _, field := range strings.Split(strings.TrimPrefix(auth, tc3Algorithm+" "), ",") {
+		kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(kv) != 2 {
+			return nil, newAPIError("AuthFailure.InvalidAuthorization", "malformed authorization field %q", field)
+		}
+		switch kv[0] {
+		case "Credential":
+			scope := strings.Split(kv[1], "/")
+			if len(scope) != 4 || scope[3] != "tc3_request" {
+				return nil, newAPIError("AuthFailure.InvalidAuthorization", "malformed credential scope %q", kv[1])
+			}
+			sig.secretId, sig.date, sig.service = scope[0], scope[1], scope[2]
+		case "SignedHeaders":
+			sig.signedHeaders = kv[1]
+		case "Signature":
+			sig.signature = kv[1]
+		}
+	}
+	if sig.secretId == "" || sig.signedHeaders == "" || sig.signature == "" {
+		return nil, newAPIError("AuthFailure.InvalidAuthorization", "incomplete authorization header")
+	}
+	return sig, nil
+}
+
+// authenticate checks the request's timestamp, credentials and signature and
+// returns the identity that signed it.
+func (s *Server) authenticate(r *http.Request, sig *signature, body []byte) (*Identity, *apiError) {
+	timestamp, err := strconv.ParseInt(header(r, "X-TC-Timestamp"), 10, 64)
+	if err != nil {
+		return nil, newAPIError("MissingParameter", "missing or invalid X-TC-Timestamp")
+	}
+	now := s.now()
+	signedAt := time.Unix(timestamp, 0).UTC()
+	if skew := now.Sub(signedAt); skew > maxClockSkew || skew < -maxClockSkew {
+		return nil, newAPIError("AuthFailure.SignatureExpire", "the request was signed at %s", signedAt)
+	}
+	if sig.date != signedAt.Format("2006-01-02") {
+		return nil, newAPIError("AuthFailure.SignatureFailure", "the credential scope date does not match the timestamp")
+	}
+
+	identity, ok := s.identities[sig.secretId]
+	if !ok {
+		return nil, newAPIError("AuthFailure.SecretIdNotFound", "secret id %s does not exist", sig.secretId)
+	}
+	if identity.Token != header(r, "X-TC-Token") {
+		return nil, newAPIError("AuthFailure.TokenFailure", "the token does not match the secret id")
+	}
+	if !identity.Expiration.IsZero() && now.After(identity.Expiration) {
+		return nil, newAPIError("AuthFailure.TokenFailure", "the temporary credentials expired at %s", identity.Expiration)
+	}
+
+	canonical, apiErr := canonicalRequest(r, sig.signedHeaders, body)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	stringToSign := fmt.Sprintf("%s\n%d\n%s/%s/tc3_request\n%s",
+		tc3Algorithm, timestamp, sig.date, sig.service, sha256Hex(canonical))
+	key := hmacSHA256([]byte("TC3"+identity.SecretKey), sig.date)
+	key = hmacSHA256(key, sig.service)
+	key = hmacSHA256(key, "tc3_request")
+	expected := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	if !hmac.Equal([]byte(expected), []byte(sig.signature)) {
+		return nil, newAPIError("AuthFailure.SignatureFailure", "the signature does not match")
+	}
+	return identity, nil
+}
+
+// canonicalRequest builds the canonical request the signature covers.
+func canonicalRequest(r *http.Request, signedHeaders string, body []byte) (string, *apiError) {
+	var headers strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		var value string
+		switch name {
+		case "host":
+			value = r.Host
+		case "content-type":
+			value = header(r, "Content-Type")
+		default:
+			value = header(r, http.CanonicalHeaderKey(name))
+		}
+		if value == "" {
+			return "", newAPIError("AuthFailure.SignatureFailure", "signed header %s is missing", name)
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	return fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n%s",
+		r.Method, "/", r.URL.RawQuery, headers.String(), signedHeaders, sha256Hex(string(body))), nil
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, msg string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(msg))
+	return mac.Sum(nil)
+}
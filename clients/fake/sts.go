@@ -0,0 +1,133 @@
+package fake
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	sts "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sts/v20180813"
+)
+
+// getCallerIdentity answers sts:GetCallerIdentity.
+func (s *Server) getCallerIdentity(identity *Identity) (interface{}, *apiError) {
+	return &sts.GetCallerIdentityResponseParams{
+		Arn:         &identity.Arn,
+		AccountId:   &identity.AccountId,
+		UserId:      &identity.UserId,
+		PrincipalId: &identity.PrincipalId,
+		Type:        &identity.Type,
+	}, nil
+}
+
+// assumeRole answers sts:AssumeRole. The temporary credentials it returns are
+// registered as a CAMRole identity until they expire.
+func (s *Server) assumeRole(identity *Identity, body []byte) (interface{}, *apiError) {
+	params := &sts.AssumeRoleRequestParams{}
+	if err := decode(body, params); err != nil {
+		return nil, err
+	}
+	if params.RoleArn == nil || *params.RoleArn == "" {
+		return nil, newAPIError("MissingParameter", "RoleArn is required")
+	}
+	if params.RoleSessionName == nil || *params.RoleSessionName == "" {
+		return nil, newAPIError("MissingParameter", "RoleSessionName is required")
+	}
+	duration := defaultSessionDuration
+	if params.DurationSeconds != nil {
+		duration = time.Duration(*params.DurationSeconds) * time.Second
+	}
+	if duration <= 0 || duration > maxSessionDuration {
+		return nil, newAPIError("InvalidParameter.OverTimeError", "DurationSeconds must be at most %d", int(maxSessionDuration.Seconds()))
+	}
+
+	uin, name, ok := parseRoleArn(*params.RoleArn)
+	if !ok {
+		return nil, newAPIError("InvalidParameter.ParamError", "malformed RoleArn %s", *params.RoleArn)
+	}
+	role := s.findRole("", name)
+	if role == nil || role.AccountId != uin {
+		return nil, newAPIError("ResourceNotFound.RoleNotFound", "role %s does not exist", *params.RoleArn)
+	}
+	if !role.trusts(identity) {
+		return nil, newAPIError("UnauthorizedOperation", "%s may not assume %s", identity.Arn, *params.RoleArn)
+	}
+
+	secretId, err := randomString("AKID", 32)
+	if err != nil {
+		return nil, newAPIError("InternalError", "%s", err)
+	}
+	secretKey, err := randomString("", 32)
+	if err != nil {
+		return nil, newAPIError("InternalError", "%s", err)
+	}
+	token, err := randomString("", 64)
+	if err != nil {
+		return nil, newAPIError("InternalError", "%s", err)
+	}
+	expiration := s.now().Add(duration).Truncate(time.Second)
+	s.identities[secretId] = &Identity{
+		SecretId:    secretId,
+		SecretKey:   secretKey,
+		Token:       token,
+		Type:        "CAMRole",
+		Arn:         fmt.Sprintf("qcs::sts:%s:assumed-role/%s", role.AccountId, role.RoleId),
+		AccountId:   role.AccountId,
+		UserId:      role.RoleId + ":" + *params.RoleSessionName,
+		PrincipalId: role.AccountId,
+		Expiration:  expiration,
+	}
+
+	expiredTime := expiration.Unix()
+	expirationString := expiration.UTC().Format(time.RFC3339)
+	return &sts.AssumeRoleResponseParams{
+		Credentials: &sts.Credentials{
+			TmpSecretId:  &secretId,
+			TmpSecretKey: &secretKey,
+			Token:        &token,
+		},
+		ExpiredTime: &expiredTime,
+		Expiration:  &expirationString,
+	}, nil
+}
+
+// parseRoleArn splits qcs::cam::uin/<uin>:roleName/<name> into its uin and name.
+func parseRoleArn(arn string) (string, string, bool) {
+	fields := strings.Split(arn, ":")
+	if len(fields) != 6 || fields[0] != "qcs" || fields[2] != "cam" {
+		return "", "", false
+	}
+	uin := strings.TrimPrefix(fields[4], "uin/")
+	name := strings.TrimPrefix(fields[5], "roleName/")
+	if uin == fields[4] || name == fields[5] || uin == "" || name == "" {
+		return "", "", false
+	}
+	return uin, name, true
+}
+
+// trusts reports whether the identity may assume the role.
+func (r *Role) trusts(identity *Identity) bool {
+	if len(r.TrustedAccounts) == 0 {
+		return true
+	}
+	for _, account := range r.TrustedAccounts {
+		if account == identity.AccountId {
+			return true
+		}
+	}
+	return false
+}
+
+// randomString returns prefix followed by random alphanumeric characters.
+func randomString(prefix string, length int) (string, error) {
+	raw, err := uuid.GenerateRandomBytes(length)
+	if err != nil {
+		return "", err
+	}
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	b := []byte(prefix)
+	for _, c := range raw {
+		b = append(b, alphabet[int(c)%len(alphabet)])
+	}
+	return string(b), nil
+}
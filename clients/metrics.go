@@ -0,0 +1,36 @@
+package clients
+
+import (
+	"errors"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	tcerr "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/errors"
+)
+
+const (
+	stsService = "sts"
+	camService = "cam"
+)
+
+// measureCall records the latency of a single attempt of an API call.
+func measureCall(service, action, region string, start time.Time, err error) {
+	metrics.MeasureSinceWithLabels([]string{"tencentcloud", "upstream", service}, start, []metrics.Label{
+		{Name: "action", Value: action},
+		{Name: "region", Value: region},
+		{Name: "error_code", Value: ErrorCode(err)},
+	})
+}
+
+// ErrorCode returns the SDK error code of err, "none" if err is nil and
+// "unknown" if it is not an SDK error.
+func ErrorCode(err error) string {
+	if err == nil {
+		return "none"
+	}
+	var sdkErr *tcerr.TencentCloudSDKError
+	if !errors.As(err, &sdkErr) {
+		return "unknown"
+	}
+	return sdkErr.GetCode()
+}
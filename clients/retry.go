@@ -0,0 +1,154 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	tcerr "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/errors"
+)
+
+const (
+	// DefaultMaxRetries is the number of retries made after the first attempt.
+	DefaultMaxRetries = 3
+	// DefaultMinRetryDelay is the backoff cap before the first retry.
+	DefaultMinRetryDelay = 100 * time.Millisecond
+	// DefaultMaxRetryDelay is the upper bound of the backoff between two attempts.
+	DefaultMaxRetryDelay = 2 * time.Second
+)
+
+// retryableCodes are SDK error codes, or code prefixes, worth retrying.
+var retryableCodes = []string{
+	"RequestLimitExceeded",
+	"InternalError",
+	"ServiceUnavailable",
+	"ResourceUnavailable",
+}
+
+// transientNetworkErrors are fragments of ClientError.NetworkError messages
+// that describe failures worth retrying.
+var transientNetworkErrors = []string{
+	"timeout",
+	"deadline exceeded",
+	"connection reset",
+	"connection refused",
+	"EOF",
+}
+
+// RetryPolicy controls how failed API calls are retried. Delays grow
+// exponentially from MinDelay up to MaxDelay, with full jitter.
+type RetryPolicy struct {
+	MaxRetries int
+	MinDelay   time.Duration
+	MaxDelay   time.Duration
+}
+
+// DefaultRetryPolicy returns the policy used when none is configured.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxRetries: DefaultMaxRetries,
+		MinDelay:   DefaultMinRetryDelay,
+		MaxDelay:   DefaultMaxRetryDelay,
+	}
+}
+
+var (
+	jitterLock sync.Mutex
+	jitter     = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// backoff returns the delay before the given retry, counted from zero.
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	ceiling := p.MinDelay
+	for i := 0; i < retry && ceiling < p.MaxDelay; i++ {
+		ceiling *= 2
+	}
+	if ceiling > p.MaxDelay {
+		ceiling = p.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	jitterLock.Lock()
+	defer jitterLock.Unlock()
+	return time.Duration(jitter.Int63n(int64(ceiling) + 1))
+}
+
+// do calls fn until it succeeds, fails with an error that is not retryable,
+// runs out of retries or ctx is done. The last error is returned.
+func (p *RetryPolicy) do(ctx context.Context, fn func() error) error {
+	if p == nil {
+		p = DefaultRetryPolicy()
+	}
+	var err error
+	for retry := 0; ; retry++ {
+		if err = fn(); err == nil || !IsRetryable(err) || retry >= p.MaxRetries {
+			return err
+		}
+		timer := time.NewTimer(p.backoff(retry))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// IsRegionalFailure reports whether err suggests the region's endpoint is
+// unreachable or unhealthy, so the call may succeed in another region.
+func IsRegionalFailure(err error) bool {
+	var sdkErr *tcerr.TencentCloudSDKError
+	if !errors.As(err, &sdkErr) {
+		return false
+	}
+	code := sdkErr.GetCode()
+	switch {
+	case code == "ClientError.NetworkError":
+		return !strings.Contains(sdkErr.GetMessage(), context.Canceled.Error())
+	case code == "ClientError.HttpStatusCodeError":
+		return strings.Contains(sdkErr.GetMessage(), "status code: 5")
+	case code == "ClientError.ParseJsonError":
+		// A body that is not an API response, typically a gateway error page.
+		return true
+	case code == "InternalError" || strings.HasPrefix(code, "InternalError."):
+		return true
+	case code == "ServiceUnavailable" || strings.HasPrefix(code, "ServiceUnavailable."):
+		return true
+	}
+	return false
+}
+
+// IsRetryable reports whether err is a throttling, server-side or transient
+// network failure that may succeed when retried.
+func IsRetryable(err error) bool {
+	var sdkErr *tcerr.TencentCloudSDKError
+	if !errors.As(err, &sdkErr) {
+		return false
+	}
+	code := sdkErr.GetCode()
+	for _, retryable := range retryableCodes {
+		if code == retryable || strings.HasPrefix(code, retryable+".") {
+			return true
+		}
+	}
+	switch code {
+	case "ClientError.NetworkError":
+		// Cancellation by the caller is not worth retrying.
+		if strings.Contains(sdkErr.GetMessage(), context.Canceled.Error()) {
+			return false
+		}
+		msg := strings.ToLower(sdkErr.GetMessage())
+		for _, fragment := range transientNetworkErrors {
+			if strings.Contains(msg, strings.ToLower(fragment)) {
+				return true
+			}
+		}
+	case "ClientError.HttpStatusCodeError":
+		return strings.Contains(sdkErr.GetMessage(), "status code: 5")
+	}
+	return false
+}
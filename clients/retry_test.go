@@ -0,0 +1,127 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	tcerr "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/errors"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"throttled", tcerr.NewTencentCloudSDKError("RequestLimitExceeded", "", "id"), true},
+		{"throttled sub code", tcerr.NewTencentCloudSDKError("RequestLimitExceeded.UinLimitExceeded", "", "id"), true},
+		{"internal error", tcerr.NewTencentCloudSDKError("InternalError", "", "id"), true},
+		{"timeout", tcerr.NewTencentCloudSDKError("ClientError.NetworkError",
+			"Fail to get response because Post \"https://sts.tencentcloudapi.com/\": i/o timeout", ""), true},
+		{"cancelled", tcerr.NewTencentCloudSDKError("ClientError.NetworkError",
+			"Fail to get response because Post \"https://sts.tencentcloudapi.com/\": context canceled", ""), false},
+		{"unknown host", tcerr.NewTencentCloudSDKError("ClientError.NetworkError",
+			"Fail to get response because dial tcp: lookup sts.tencentcloudapi.com: no such host", ""), false},
+		{"bad gateway", tcerr.NewTencentCloudSDKError("ClientError.HttpStatusCodeError",
+			"Request fail with http status code: 502 Bad Gateway, with body: ", ""), true},
+		{"not found", tcerr.NewTencentCloudSDKError("ClientError.HttpStatusCodeError",
+			"Request fail with http status code: 404 Not Found, with body: ", ""), false},
+		{"auth failure", tcerr.NewTencentCloudSDKError("AuthFailure.SignatureFailure", "", "id"), false},
+		{"plain error", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_Do(t *testing.T) {
+	policy := &RetryPolicy{MaxRetries: 2, MinDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+	throttled := tcerr.NewTencentCloudSDKError("RequestLimitExceeded", "", "id")
+
+	attempts := 0
+	err := policy.do(context.Background(), func() error {
+		attempts++
+		return throttled
+	})
+	if err != throttled {
+		t.Fatalf("expected the last error but received %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts but received %d", attempts)
+	}
+
+	attempts = 0
+	err = policy.do(context.Background(), func() error {
+		attempts++
+		if attempts < 2 {
+			return throttled
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Fatalf("expected success on the second attempt, received %v after %d attempts", err, attempts)
+	}
+
+	attempts = 0
+	err = policy.do(context.Background(), func() error {
+		attempts++
+		return tcerr.NewTencentCloudSDKError("AuthFailure.SecretIdNotFound", "", "id")
+	})
+	if err == nil || attempts != 1 {
+		t.Fatalf("expected no retries for a permanent error, received %d attempts", attempts)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts = 0
+	slow := &RetryPolicy{MaxRetries: 5, MinDelay: time.Hour, MaxDelay: time.Hour}
+	err = slow.do(ctx, func() error {
+		attempts++
+		return throttled
+	})
+	if err != throttled || attempts != 1 {
+		t.Fatalf("expected to stop once ctx is done, received %v after %d attempts", err, attempts)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := &RetryPolicy{MinDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for retry := 0; retry < 10; retry++ {
+		if d := policy.backoff(retry); d < 0 || d > time.Second {
+			t.Fatalf("backoff(%d) = %s is out of bounds", retry, d)
+		}
+	}
+}
+
+func TestIsRegionalFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"unknown host", tcerr.NewTencentCloudSDKError("ClientError.NetworkError",
+			"Fail to get response because dial tcp: lookup sts.ap-shanghai.tencentcloudapi.com: no such host", ""), true},
+		{"cancelled", tcerr.NewTencentCloudSDKError("ClientError.NetworkError",
+			"Fail to get response because Post \"https://sts.tencentcloudapi.com/\": context canceled", ""), false},
+		{"service unavailable", tcerr.NewTencentCloudSDKError("ClientError.HttpStatusCodeError",
+			"Request fail with http status code: 503 Service Unavailable, with body: ", ""), true},
+		{"gateway error page", tcerr.NewTencentCloudSDKError("ClientError.ParseJsonError",
+			"Fail to parse json content: <html>502 Bad Gateway</html>", ""), true},
+		{"internal error", tcerr.NewTencentCloudSDKError("InternalError", "", "id"), true},
+		{"throttled", tcerr.NewTencentCloudSDKError("RequestLimitExceeded", "", "id"), false},
+		{"auth failure", tcerr.NewTencentCloudSDKError("AuthFailure.TokenFailure", "", "id"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRegionalFailure(tt.err); got != tt.want {
+				t.Errorf("IsRegionalFailure() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,29 +1,29 @@
 package clients
 
 import (
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
 	sts "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sts/v20180813"
 )
 
-// NewStsClient init New STS Client
-func NewStsClient(secretId, secretKey, token, region string) (*STSClient, error) {
-	creds, err := ChainedCredsToCli(secretId, secretKey, token)
+// NewSTSClientWithCreds init New STS Client from already resolved credentials
+func NewSTSClientWithCreds(creds common.CredentialIface, config *Config) (*STSClient, error) {
+	client, err := sts.NewClient(creds, config.region(), config.profile())
 	if err != nil {
 		return nil, err
 	}
-	profile := profile.NewClientProfile()
-	profile.Language = "en-US"
-	profile.HttpProfile.ReqTimeout = 90
-	client, err := sts.NewClient(creds, region, profile)
-	if err != nil {
-		return nil, err
-	}
-	return &STSClient{client: client}, nil
+	config.apply(&client.Client)
+	return &STSClient{client: client, region: config.region(), retry: config.retryPolicy()}, nil
 }
 
 // STSClient STS Client
 type STSClient struct {
 	client *sts.Client
+	region string
+	retry  *RetryPolicy
 }
 
 // CallerIdentityRsp caller identity response
@@ -37,9 +37,14 @@ type CallerIdentityRsp struct {
 }
 
 // GetCallerIdentity get caller identity
-func (c *STSClient) GetCallerIdentity() (rsp *CallerIdentityRsp, err error) {
-	req := sts.NewGetCallerIdentityRequest()
-	callerIdentityRsp, err := c.client.GetCallerIdentity(req)
+func (c *STSClient) GetCallerIdentity(ctx context.Context) (rsp *CallerIdentityRsp, err error) {
+	var callerIdentityRsp *sts.GetCallerIdentityResponse
+	err = c.retry.do(ctx, func() (err error) {
+		start := time.Now()
+		callerIdentityRsp, err = c.client.GetCallerIdentityWithContext(ctx, sts.NewGetCallerIdentityRequest())
+		measureCall(stsService, "GetCallerIdentity", c.region, start, err)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -52,3 +57,29 @@ func (c *STSClient) GetCallerIdentity() (rsp *CallerIdentityRsp, err error) {
 		RequestId:   *callerIdentityRsp.Response.RequestId,
 	}, nil
 }
+
+// AssumeRole returns temporary credentials of roleArn and when they expire.
+func (c *STSClient) AssumeRole(ctx context.Context, roleArn, sessionName string) (common.CredentialIface, time.Time, error) {
+	req := sts.NewAssumeRoleRequest()
+	req.RoleArn = &roleArn
+	req.RoleSessionName = &sessionName
+	var rsp *sts.AssumeRoleResponse
+	err := c.retry.do(ctx, func() (err error) {
+		start := time.Now()
+		rsp, err = c.client.AssumeRoleWithContext(ctx, req)
+		measureCall(stsService, "AssumeRole", c.region, start, err)
+		return err
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if rsp.Response == nil || rsp.Response.Credentials == nil {
+		return nil, time.Time{}, fmt.Errorf("no credentials returned when assuming %s", roleArn)
+	}
+	var expiration time.Time
+	if rsp.Response.ExpiredTime != nil {
+		expiration = time.Unix(*rsp.Response.ExpiredTime, 0)
+	}
+	creds := rsp.Response.Credentials
+	return common.NewTokenCredential(*creds.TmpSecretId, *creds.TmpSecretKey, *creds.Token), expiration, nil
+}
@@ -0,0 +1,51 @@
+// Command fake-tencentcloud serves the fake TencentCloud STS and CAM APIs
+// from package clients/fake, loaded with identities, roles and faults from a
+// JSON scenario file.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault-plugin-auth-tencentcloud/clients/fake"
+)
+
+func main() {
+	logger := hclog.New(&hclog.LoggerOptions{Name: "fake-tencentcloud"})
+
+	flags := flag.NewFlagSet("fake-tencentcloud", flag.ExitOnError)
+	listen := flags.String("listen", "127.0.0.1:9100", "address to listen on")
+	scenarioPath := flags.String("scenario", "", "path of the JSON scenario file")
+	tlsCert := flags.String("tls-cert", "", "path of the PEM-encoded TLS certificate, to serve HTTPS")
+	tlsKey := flags.String("tls-key", "", "path of the PEM-encoded TLS key")
+	flags.Parse(os.Args[1:])
+
+	scenario := &fake.Scenario{}
+	if *scenarioPath != "" {
+		raw, err := ioutil.ReadFile(*scenarioPath)
+		if err != nil {
+			logger.Error("unable to read scenario", "error", err)
+			os.Exit(1)
+		}
+		if err := json.Unmarshal(raw, scenario); err != nil {
+			logger.Error("unable to parse scenario", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	server := fake.NewFromScenario(scenario)
+	logger.Info("serving the fake TencentCloud API", "address", *listen,
+		"identities", len(scenario.Identities), "roles", len(scenario.Roles), "faults", len(scenario.Faults))
+	var err error
+	if *tlsCert != "" {
+		err = http.ListenAndServeTLS(*listen, *tlsCert, *tlsKey, server)
+	} else {
+		err = http.ListenAndServe(*listen, server)
+	}
+	logger.Error("server shutting down", "error", err)
+	os.Exit(1)
+}
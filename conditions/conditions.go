@@ -0,0 +1,652 @@
+// Package conditions implements a small expression language for checking
+// attributes of a caller, such as
+//
+//	account_id in ["1000262888"] && session_name.startsWith("deploy-")
+//
+// Expressions are made of string, integer, boolean and list literals,
+// attribute names, the operators ||, &&, !, ==, !=, <, <=, >, >= and in,
+// indexing with [] and the methods startsWith, endsWith, contains, matches,
+// inCIDR, lower, upper and size. There are no loops and no user-defined
+// functions, and the length and nesting of expressions are bounded, so
+// evaluating an expression always terminates quickly.
+package conditions
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// MaxLength is the maximum length of an expression in bytes.
+	MaxLength = 1024
+	// maxDepth is the maximum nesting of an expression.
+	maxDepth = 32
+)
+
+// Attributes are the values an expression is evaluated against, by name.
+// Values are strings, int64s, bools, []strings or map[string]strings.
+type Attributes map[string]interface{}
+
+// Expr is a parsed expression.
+type Expr struct {
+	src   string
+	root  node
+	names map[string]bool
+}
+
+// Parse parses an expression.
+func Parse(src string) (*Expr, error) {
+	if len(src) > MaxLength {
+		return nil, fmt.Errorf("expression is longer than %d bytes", MaxLength)
+	}
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, names: map[string]bool{}}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at %d", t, t.pos)
+	}
+	return &Expr{src: src, root: root, names: p.names}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Attributes returns the names of the attributes the expression uses, sorted.
+func (e *Expr) Attributes() []string {
+	names := make([]string, 0, len(e.names))
+	for name := range e.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Uses reports whether the expression uses the attribute name.
+func (e *Expr) Uses(name string) bool {
+	return e.names[name]
+}
+
+// Eval evaluates the expression. Expressions that do not evaluate to a
+// boolean, use unknown attributes or mix up types return an error.
+func (e *Expr) Eval(attrs Attributes) (bool, error) {
+	v, err := e.root.eval(attrs)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression is a %s, not a bool", typeName(v))
+	}
+	return b, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+	names  map[string]bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(punct string) bool {
+	if t := p.peek(); t.kind == tokenPunct && t.text == punct {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.accept(punct) {
+		t := p.peek()
+		return fmt.Errorf("expected %q but found %s at %d", punct, t, t.pos)
+	}
+	return nil
+}
+
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxDepth {
+		return fmt.Errorf("expression is nested more than %d levels deep", maxDepth)
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) parseOr() (node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	x, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		y, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		x = &logicalNode{or: true, x: x, y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	x, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		y, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		x = &logicalNode{x: x, y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseComparison() (node, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	op := ""
+	switch {
+	case t.kind == tokenPunct && strings.Contains(" == != < <= > >= ", " "+t.text+" "):
+		op = t.text
+	case t.kind == tokenIdent && t.text == "in":
+		op = "in"
+	default:
+		return x, nil
+	}
+	p.next()
+	y, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &comparisonNode{op: op, x: x, y: y}, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.accept("!") {
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{x: x}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			t := p.next()
+			if t.kind != tokenIdent {
+				return nil, fmt.Errorf("expected a method name but found %s at %d", t, t.pos)
+			}
+			if _, ok := methods[t.text]; !ok {
+				return nil, fmt.Errorf("unknown method %q at %d", t.text, t.pos)
+			}
+			if err := p.expect("("); err != nil {
+				return nil, err
+			}
+			args, err := p.parseList(")")
+			if err != nil {
+				return nil, err
+			}
+			x = &callNode{recv: x, method: t.text, args: args}
+		case p.accept("["):
+			index, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = &indexNode{x: x, index: index}
+		default:
+			return x, nil
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString, tokenInt:
+		return &literalNode{value: t.value}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "in":
+			return nil, fmt.Errorf("unexpected %s at %d", t, t.pos)
+		}
+		p.names[t.text] = true
+		return &identNode{name: t.text}, nil
+	case tokenPunct:
+		switch t.text {
+		case "(":
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		case "[":
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listNode{items: items}, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %s at %d", t, t.pos)
+}
+
+// parseList parses comma-separated expressions up to the closing punct.
+func (p *parser) parseList(closing string) ([]node, error) {
+	var items []node
+	if p.accept(closing) {
+		return items, nil
+	}
+	for {
+		item, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if p.accept(closing) {
+			return items, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+type node interface {
+	eval(attrs Attributes) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(Attributes) (interface{}, error) {
+	return n.value, nil
+}
+
+type identNode struct {
+	name string
+}
+
+func (n *identNode) eval(attrs Attributes) (interface{}, error) {
+	v, ok := attrs[n.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown attribute %q", n.name)
+	}
+	switch v := v.(type) {
+	case int:
+		return int64(v), nil
+	case []string:
+		items := make([]interface{}, len(v))
+		for i, s := range v {
+			items[i] = s
+		}
+		return items, nil
+	case string, int64, bool, map[string]string:
+		return v, nil
+	}
+	return nil, fmt.Errorf("attribute %q has unsupported type %T", n.name, v)
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n *listNode) eval(attrs Attributes) (interface{}, error) {
+	items := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		v, err := item.eval(attrs)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = v
+	}
+	return items, nil
+}
+
+type notNode struct {
+	x node
+}
+
+func (n *notNode) eval(attrs Attributes) (interface{}, error) {
+	b, err := evalBool(n.x, attrs, "!")
+	if err != nil {
+		return nil, err
+	}
+	return !b, nil
+}
+
+// logicalNode is && or ||. The right operand is only evaluated if needed.
+type logicalNode struct {
+	or   bool
+	x, y node
+}
+
+func (n *logicalNode) eval(attrs Attributes) (interface{}, error) {
+	op := "&&"
+	if n.or {
+		op = "||"
+	}
+	x, err := evalBool(n.x, attrs, op)
+	if err != nil {
+		return nil, err
+	}
+	if x == n.or {
+		return x, nil
+	}
+	return evalBool(n.y, attrs, op)
+}
+
+func evalBool(n node, attrs Attributes, op string) (bool, error) {
+	v, err := n.eval(attrs)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s needs bools, not a %s", op, typeName(v))
+	}
+	return b, nil
+}
+
+type comparisonNode struct {
+	op   string
+	x, y node
+}
+
+func (n *comparisonNode) eval(attrs Attributes) (interface{}, error) {
+	x, err := n.x.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	y, err := n.y.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "in" {
+		switch y := y.(type) {
+		case []interface{}:
+			for _, item := range y {
+				if equal, err := equals(x, item); err == nil && equal {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]string:
+			key, ok := x.(string)
+			if !ok {
+				return nil, fmt.Errorf("in a map needs a string key, not a %s", typeName(x))
+			}
+			_, found := y[key]
+			return found, nil
+		}
+		return nil, fmt.Errorf("in needs a list or a map, not a %s", typeName(y))
+	}
+
+	switch n.op {
+	case "==", "!=":
+		equal, err := equals(x, y)
+		if err != nil {
+			return nil, err
+		}
+		return equal == (n.op == "=="), nil
+	}
+	var cmp int
+	switch x := x.(type) {
+	case int64:
+		y, ok := y.(int64)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare an int with a %s", typeName(y))
+		}
+		switch {
+		case x < y:
+			cmp = -1
+		case x > y:
+			cmp = 1
+		}
+	case string:
+		y, ok := y.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare a string with a %s", typeName(y))
+		}
+		cmp = strings.Compare(x, y)
+	default:
+		return nil, fmt.Errorf("%s needs ints or strings, not a %s", n.op, typeName(x))
+	}
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+// equals compares two scalars of the same type.
+func equals(x, y interface{}) (bool, error) {
+	switch x.(type) {
+	case string, int64, bool:
+		if typeName(x) != typeName(y) {
+			return false, fmt.Errorf("cannot compare a %s with a %s", typeName(x), typeName(y))
+		}
+		return x == y, nil
+	}
+	return false, fmt.Errorf("cannot compare a %s", typeName(x))
+}
+
+type indexNode struct {
+	x, index node
+}
+
+func (n *indexNode) eval(attrs Attributes) (interface{}, error) {
+	x, err := n.x.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	switch x := x.(type) {
+	case map[string]string:
+		key, ok := index.(string)
+		if !ok {
+			return nil, fmt.Errorf("a map index must be a string, not a %s", typeName(index))
+		}
+		// Missing keys are empty, so tags that are not set can be compared.
+		return x[key], nil
+	case []interface{}:
+		i, ok := index.(int64)
+		if !ok {
+			return nil, fmt.Errorf("a list index must be an int, not a %s", typeName(index))
+		}
+		if i < 0 || i >= int64(len(x)) {
+			return nil, fmt.Errorf("index %d is out of range", i)
+		}
+		return x[i], nil
+	}
+	return nil, fmt.Errorf("cannot index a %s", typeName(x))
+}
+
+type callNode struct {
+	recv   node
+	method string
+	args   []node
+}
+
+// method implements a method of strings, lists or maps.
+type method struct {
+	args int
+	call func(recv interface{}, args []interface{}) (interface{}, error)
+}
+
+var methods = map[string]method{
+	"startsWith": {args: 1, call: stringMethod(func(s, arg string) (interface{}, error) {
+		return strings.HasPrefix(s, arg), nil
+	})},
+	"endsWith": {args: 1, call: stringMethod(func(s, arg string) (interface{}, error) {
+		return strings.HasSuffix(s, arg), nil
+	})},
+	"contains": {args: 1, call: func(recv interface{}, args []interface{}) (interface{}, error) {
+		if list, ok := recv.([]interface{}); ok {
+			for _, item := range list {
+				if equal, err := equals(item, args[0]); err == nil && equal {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		return stringMethod(func(s, arg string) (interface{}, error) {
+			return strings.Contains(s, arg), nil
+		})(recv, args)
+	}},
+	// matches uses RE2 syntax, which matches in linear time.
+	"matches": {args: 1, call: stringMethod(func(s, pattern string) (interface{}, error) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %s", err)
+		}
+		return re.MatchString(s), nil
+	})},
+	"inCIDR": {args: 1, call: stringMethod(func(s, cidr string) (interface{}, error) {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", cidr)
+		}
+		ip := net.ParseIP(s)
+		return ip != nil && network.Contains(ip), nil
+	})},
+	"lower": {call: func(recv interface{}, args []interface{}) (interface{}, error) {
+		s, ok := recv.(string)
+		if !ok {
+			return nil, fmt.Errorf("lower needs a string, not a %s", typeName(recv))
+		}
+		return strings.ToLower(s), nil
+	}},
+	"upper": {call: func(recv interface{}, args []interface{}) (interface{}, error) {
+		s, ok := recv.(string)
+		if !ok {
+			return nil, fmt.Errorf("upper needs a string, not a %s", typeName(recv))
+		}
+		return strings.ToUpper(s), nil
+	}},
+	"size": {call: func(recv interface{}, args []interface{}) (interface{}, error) {
+		switch v := recv.(type) {
+		case string:
+			return int64(len(v)), nil
+		case []interface{}:
+			return int64(len(v)), nil
+		case map[string]string:
+			return int64(len(v)), nil
+		}
+		return nil, fmt.Errorf("size needs a string, list or map, not a %s", typeName(recv))
+	}},
+}
+
+// stringMethod adapts a method of a string with one string argument.
+func stringMethod(fn func(s, arg string) (interface{}, error)) func(interface{}, []interface{}) (interface{}, error) {
+	return func(recv interface{}, args []interface{}) (interface{}, error) {
+		s, ok := recv.(string)
+		if !ok {
+			return nil, fmt.Errorf("needs a string receiver, not a %s", typeName(recv))
+		}
+		arg, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("needs a string argument, not a %s", typeName(args[0]))
+		}
+		return fn(s, arg)
+	}
+}
+
+func (n *callNode) eval(attrs Attributes) (interface{}, error) {
+	m := methods[n.method]
+	if len(n.args) != m.args {
+		return nil, fmt.Errorf("%s takes %d arguments, not %d", n.method, m.args, len(n.args))
+	}
+	recv, err := n.recv.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		if args[i], err = arg.eval(attrs); err != nil {
+			return nil, err
+		}
+	}
+	v, err := m.call(recv, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", n.method, err)
+	}
+	return v, nil
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case int64:
+		return "int"
+	case bool:
+		return "bool"
+	case []interface{}:
+		return "list"
+	case map[string]string:
+		return "map"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,120 @@
+package conditions
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	attrs := Attributes{
+		"account_id":   "1000262888",
+		"session_name": "deploy-42",
+		"source_ip":    "10.1.2.3",
+		"hour":         int64(14),
+		"groups":       []string{"dev", "ops"},
+		"tags":         map[string]string{"team": "search"},
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`account_id in ["1000262888"] && session_name.startsWith("deploy-")`, true},
+		{`account_id in ['1000262999']`, false},
+		{`!(account_id == "1000262888")`, false},
+		{`account_id != "1" || hour > 100`, true},
+		{`hour >= 9 && hour < 18`, true},
+		{`"a" < "b"`, true},
+		{`session_name.endsWith("42") && session_name.contains("oy-")`, true},
+		{`session_name.matches("^deploy-[0-9]+$")`, true},
+		{`source_ip.inCIDR("10.0.0.0/8") && !source_ip.inCIDR("192.168.0.0/16")`, true},
+		{`session_name.upper().lower() == session_name`, true},
+		{`session_name.size() == 9 && groups.size() == 2 && tags.size() == 1`, true},
+		{`groups.contains("ops") && "dev" in groups && groups[0] == "dev"`, true},
+		{`tags["team"] == "search" && "team" in tags && tags["owner"] == ""`, true},
+		{`true && (false || [1, 2][1] == 2)`, true},
+		// The right operand is not evaluated once the result is known.
+		{`false && unknown`, false},
+		{`true || unknown`, true},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.expr, err)
+		}
+		got, err := expr.Eval(attrs)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.expr, err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s: got %t, want %t", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	attrs := Attributes{
+		"account_id": "1000262888",
+		"hour":       int64(14),
+		"groups":     []string{"dev"},
+	}
+	for _, src := range []string{
+		`account_id`,
+		`unknown == "x"`,
+		`hour == "14"`,
+		`hour < "9"`,
+		`!hour`,
+		`hour && true`,
+		`hour.startsWith("1")`,
+		`account_id.matches("(")`,
+		`account_id.inCIDR("10.0.0.0")`,
+		`groups[1] == "ops"`,
+		`account_id in "1000262888"`,
+		`account_id.lower("x") == ""`,
+	} {
+		expr, err := Parse(src)
+		if err != nil {
+			t.Fatalf("%s: %s", src, err)
+		}
+		if ok, err := expr.Eval(attrs); err == nil || ok {
+			t.Fatalf("%s: expected an error, got %t", src, ok)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		``,
+		`account_id ==`,
+		`account_id == "x`,
+		`account_id == 'x\q'`,
+		`(account_id == "x"`,
+		`account_id == "x")`,
+		`account_id.unknown()`,
+		`account_id.lower`,
+		`account_id = "x"`,
+		`a == b == c`,
+		`["a",]`,
+		`99999999999999999999 > 1`,
+		strings.Repeat("(", 40) + "true" + strings.Repeat(")", 40),
+		strings.Repeat("!", 40) + "true",
+		`"` + strings.Repeat("a", MaxLength) + `"`,
+	} {
+		if _, err := Parse(src); err == nil {
+			t.Fatalf("%s: expected an error", src)
+		}
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	expr, err := Parse(`tags["team"] == "search" || account_id.startsWith(session_name) && true`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(expr.Attributes(), ",")
+	if got != "account_id,session_name,tags" {
+		t.Fatalf("unexpected attributes %s", got)
+	}
+	if !expr.Uses("tags") || expr.Uses("hour") {
+		t.Fatal("unexpected uses")
+	}
+}
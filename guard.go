@@ -0,0 +1,177 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/time/rate"
+)
+
+// guard checks logins against config/guard before any TencentCloud call.
+type guard struct {
+	config *guardConfig
+	cidrs  []*net.IPNet
+
+	// lock makes looking up and adding the limiter of an address atomic.
+	lock sync.Mutex
+	// limiters holds a *rate.Limiter per address, dropping the least
+	// recently used ones beyond maxSourceLimiters.
+	limiters *lru.Cache
+}
+
+const (
+	// maxSourceLimiters is the number of addresses whose rates are tracked.
+	maxSourceLimiters = 4096
+	// unknownSource is the address of logins whose address is not known,
+	// which share one rate.
+	unknownSource = "unknown"
+)
+
+func newGuard(config *guardConfig) (*guard, error) {
+	limiters, err := lru.New(maxSourceLimiters)
+	if err != nil {
+		return nil, err
+	}
+	g := &guard{
+		config:   config,
+		limiters: limiters,
+	}
+	for _, cidr := range config.AllowedSourceCIDRs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			g.cidrs = append(g.cidrs, network)
+		}
+	}
+	return g, nil
+}
+
+// check fails logins from addresses outside the allowed source CIDRs, and
+// logins beyond the rate limit of their address.
+func (g *guard) check(ip string, now time.Time) error {
+	if len(g.cidrs) > 0 {
+		addr := net.ParseIP(ip)
+		allowed := false
+		for _, network := range g.cidrs {
+			if addr != nil && network.Contains(addr) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return newLoginError(errCodeSourceDenied, "logins are not accepted from this address",
+				fmt.Errorf("%q is not in the mount's allowed source CIDRs", ip))
+		}
+	}
+	if ip == "" {
+		ip = unknownSource
+	}
+	if g.config.SourceRateLimit > 0 && !g.allow(ip, now) {
+		return newLoginError(errCodeRateLimited, "too many logins from this address, try again later",
+			fmt.Errorf("%q exceeded %g logins per second", ip, g.config.SourceRateLimit))
+	}
+	return nil
+}
+
+func (g *guard) allow(ip string, now time.Time) bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	var limiter *rate.Limiter
+	if cached, ok := g.limiters.Get(ip); ok {
+		limiter = cached.(*rate.Limiter)
+	} else {
+		limiter = rate.NewLimiter(rate.Limit(g.config.SourceRateLimit), g.config.burst())
+		g.limiters.Add(ip, limiter)
+	}
+	return limiter.AllowN(now, 1)
+}
+
+// checkGuard runs the guard of the mount against the request.
+func (b *backend) checkGuard(ctx context.Context, req *logical.Request) error {
+	g, err := b.getGuard(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+	return g.check(sourceIP(req), time.Now())
+}
+
+// getGuard returns the guard, reading config/guard on first use.
+func (b *backend) getGuard(ctx context.Context, s logical.Storage) (*guard, error) {
+	b.guardLock.RLock()
+	g := b.guard
+	b.guardLock.RUnlock()
+	if g != nil {
+		return g, nil
+	}
+
+	b.guardLock.Lock()
+	defer b.guardLock.Unlock()
+	if b.guard != nil {
+		return b.guard, nil
+	}
+	config, err := readGuardConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if b.guard, err = newGuard(config); err != nil {
+		return nil, err
+	}
+	return b.guard, nil
+}
+
+// resetGuard drops the guard, and with it the rates of all addresses, so the
+// next login reads config/guard again.
+func (b *backend) resetGuard() {
+	b.guardLock.Lock()
+	defer b.guardLock.Unlock()
+	b.guard = nil
+}
+
+// fieldRule bounds the length and characters of a login field.
+type fieldRule struct {
+	field  string
+	maxLen int
+	valid  func(c byte) bool
+}
+
+// loginFieldRules are checked before the credentials are sent to STS.
+var loginFieldRules = []fieldRule{
+	{field: "secret_id", maxLen: 1024, valid: isVisibleASCII},
+	{field: "secret_key", maxLen: 1024, valid: isVisibleASCII},
+	{field: "token", maxLen: 4096, valid: isVisibleASCII},
+	{field: "region", maxLen: 64, valid: isRegionChar},
+	{field: "role", maxLen: 256, valid: isVisibleASCII},
+}
+
+func isVisibleASCII(c byte) bool {
+	return c > ' ' && c < 0x7f
+}
+
+func isRegionChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-'
+}
+
+// checkFieldRules checks the login fields of data against loginFieldRules.
+func checkFieldRules(data *framework.FieldData) error {
+	for _, rule := range loginFieldRules {
+		if _, ok := data.Schema[rule.field]; !ok {
+			continue
+		}
+		value := data.Get(rule.field).(string)
+		if len(value) > rule.maxLen {
+			return newLoginError(errCodeInvalidRequest,
+				fmt.Sprintf("%s is longer than %d bytes", rule.field, rule.maxLen), nil)
+		}
+		for i := 0; i < len(value); i++ {
+			if !rule.valid(value[i]) {
+				return newLoginError(errCodeInvalidRequest,
+					fmt.Sprintf("%s contains an invalid character at byte %d", rule.field, i), nil)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,364 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// lockoutPrefix holds one lockoutEntry per principal with recent failed
+// logins, at lockoutPrefix<base64url of the principal>.
+const lockoutPrefix = "lockout/"
+
+// lockoutEntry tracks the failed logins of one principal.
+type lockoutEntry struct {
+	// Principal is the arn of the caller's CAM role, and Arn the arn its last
+	// failed login was verified as.
+	Principal string `json:"principal"`
+	Arn       string `json:"arn"`
+	// Failures counts the failures since WindowStart, in total and by role.
+	Failures       int            `json:"failures"`
+	FailuresByRole map[string]int `json:"failures_by_role"`
+	WindowStart    time.Time      `json:"window_start"`
+	LastFailure    time.Time      `json:"last_failure"`
+	// LockedUntil, if in the future, is when the lockout ends.
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+func (e *lockoutEntry) locked(now time.Time) bool {
+	return now.Before(e.LockedUntil)
+}
+
+// stale reports whether the entry neither counts towards nor causes a lockout.
+func (e *lockoutEntry) stale(config *lockoutConfig, now time.Time) bool {
+	return !e.locked(now) && now.Sub(e.WindowStart) >= config.FailureWindow
+}
+
+func lockoutID(principal string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(principal))
+}
+
+func readLockoutEntry(ctx context.Context, s logical.Storage, id string) (*lockoutEntry, error) {
+	entry, err := s.Get(ctx, lockoutPrefix+id)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	result := &lockoutEntry{}
+	if err := entry.DecodeJSON(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// lockoutIndex knows which principals have a lockout entry, so that logins of
+// the others do not touch storage. It is filled by listing lockoutPrefix on
+// first use, then kept up to date by trackLogin, the lockout endpoints and
+// invalidate. An indexed id whose entry is gone only costs a storage read.
+type lockoutIndex struct {
+	// locks serialize the updates of each principal's entry.
+	locks []*locksutil.LockEntry
+
+	lock   sync.RWMutex
+	loaded bool
+	ids    map[string]bool
+}
+
+func newLockoutIndex() *lockoutIndex {
+	return &lockoutIndex{
+		locks: locksutil.CreateLocks(),
+		ids:   make(map[string]bool),
+	}
+}
+
+// lockFor returns the lock of the entry of id.
+func (x *lockoutIndex) lockFor(id string) *locksutil.LockEntry {
+	return locksutil.LockForKey(x.locks, id)
+}
+
+// has reports whether id may have an entry.
+func (x *lockoutIndex) has(ctx context.Context, s logical.Storage, id string) (bool, error) {
+	x.lock.RLock()
+	loaded, ok := x.loaded, x.ids[id]
+	x.lock.RUnlock()
+	if loaded {
+		return ok, nil
+	}
+
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	if !x.loaded {
+		ids, err := s.List(ctx, lockoutPrefix)
+		if err != nil {
+			return false, err
+		}
+		for _, id := range ids {
+			x.ids[id] = true
+		}
+		x.loaded = true
+	}
+	return x.ids[id], nil
+}
+
+func (x *lockoutIndex) add(id string) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	x.ids[id] = true
+}
+
+func (x *lockoutIndex) remove(id string) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	delete(x.ids, id)
+}
+
+// isReadOnly reports whether err is the error of a write to the storage of a
+// performance standby. Storage errors reach the plugin as their message only.
+func isReadOnly(err error) bool {
+	return errors.Is(err, logical.ErrReadOnly) || errwrap.Contains(err, logical.ErrReadOnly.Error())
+}
+
+// uncountedFailures are the failure codes that do not count towards a
+// lockout: failures of STS or CAM, which are not the caller's, denials caused
+// by the lockout and rate limits themselves, and denials of an operator's
+// choice, which retrying cannot get around.
+var uncountedFailures = map[string]bool{
+	errCodeInternal:            true,
+	errCodeUpstreamUnavailable: true,
+	errCodeUpstreamError:       true,
+	errCodeLockedOut:           true,
+	errCodeRateLimited:         true,
+	errCodeRoleDisabled:        true,
+	errCodeLoginTimeDenied:     true,
+}
+
+// checkLockout fails logins of a locked out principal.
+func (b *backend) checkLockout(ctx context.Context, s logical.Storage, principal string) error {
+	if principal == "" {
+		return nil
+	}
+	config, err := b.getLockoutConfig(ctx, s)
+	if err != nil || !config.enabled() {
+		return err
+	}
+	id := lockoutID(principal)
+	indexed, err := b.lockouts.has(ctx, s, id)
+	if err != nil || !indexed {
+		return err
+	}
+	entry, err := readLockoutEntry(ctx, s, id)
+	if err != nil {
+		return err
+	}
+	if entry != nil && entry.locked(time.Now()) {
+		return newLoginError(errCodeLockedOut, "too many failed logins, try again later",
+			fmt.Errorf("%s is locked out until %s", principal, entry.LockedUntil.Format(time.RFC3339)))
+	}
+	return nil
+}
+
+// trackLogin counts a failed login of a verified caller towards its lockout,
+// and clears the failures of a caller that logged in. Only principals with
+// an entry touch storage when they log in.
+func (b *backend) trackLogin(ctx context.Context, s logical.Storage, attempt *loginAttempt, loginErr error) error {
+	if attempt.principal == "" {
+		return nil
+	}
+	config, err := b.getLockoutConfig(ctx, s)
+	if err != nil || !config.enabled() {
+		return err
+	}
+	if loginErr != nil && uncountedFailures[loginErrorCode(loginErr)] {
+		return nil
+	}
+
+	id := lockoutID(attempt.principal)
+	lock := b.lockouts.lockFor(id)
+	lock.Lock()
+	defer lock.Unlock()
+	indexed, err := b.lockouts.has(ctx, s, id)
+	if err != nil {
+		return err
+	}
+	var entry *lockoutEntry
+	if indexed {
+		if entry, err = readLockoutEntry(ctx, s, id); err != nil {
+			return err
+		}
+	}
+	if loginErr == nil {
+		if entry != nil {
+			if err := s.Delete(ctx, lockoutPrefix+id); err != nil {
+				return err
+			}
+		}
+		if indexed {
+			b.lockouts.remove(id)
+		}
+		return nil
+	}
+
+	now := time.Now().UTC()
+	if entry == nil || entry.stale(config, now) {
+		entry = &lockoutEntry{
+			Principal:      attempt.principal,
+			Arn:            attempt.arn,
+			FailuresByRole: map[string]int{},
+			WindowStart:    now,
+		}
+	}
+	role := attempt.roleName
+	if role == "" {
+		role = attempt.requestedRole
+	}
+	entry.Failures++
+	entry.FailuresByRole[role]++
+	entry.LastFailure = now
+	if !entry.locked(now) && entry.Failures >= config.MaxFailures {
+		entry.LockedUntil = now.Add(config.LockoutDuration)
+		b.Logger().Warn("locking out principal after failed logins", "principal", attempt.principal,
+			"arn", attempt.arn, "failures", entry.Failures, "locked_until", entry.LockedUntil.Format(time.RFC3339))
+	}
+	storageEntry, err := logical.StorageEntryJSON(lockoutPrefix+id, entry)
+	if err != nil {
+		return err
+	}
+	if err := s.Put(ctx, storageEntry); err != nil {
+		return err
+	}
+	b.lockouts.add(id)
+	return nil
+}
+
+// clearLockout deletes the entry of id.
+func (b *backend) clearLockout(ctx context.Context, s logical.Storage, id string) error {
+	lock := b.lockouts.lockFor(id)
+	lock.Lock()
+	defer lock.Unlock()
+	if err := s.Delete(ctx, lockoutPrefix+id); err != nil {
+		return err
+	}
+	b.lockouts.remove(id)
+	return nil
+}
+
+// pruneLockouts deletes the entries of principals that are neither locked
+// out nor have recent failures. Performance standbys leave it to the active
+// node.
+func (b *backend) pruneLockouts(ctx context.Context, req *logical.Request) error {
+	config, err := b.getLockoutConfig(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+	ids, err := req.Storage.List(ctx, lockoutPrefix)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, id := range ids {
+		if err := b.pruneLockout(ctx, req.Storage, config, id, now); err != nil {
+			if isReadOnly(err) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *backend) pruneLockout(ctx context.Context, s logical.Storage, config *lockoutConfig, id string,
+	now time.Time) error {
+	lock := b.lockouts.lockFor(id)
+	lock.Lock()
+	defer lock.Unlock()
+	entry, err := readLockoutEntry(ctx, s, id)
+	if err != nil {
+		return err
+	}
+	if entry != nil && (!config.enabled() || entry.stale(config, now)) {
+		if err := s.Delete(ctx, lockoutPrefix+id); err != nil {
+			return err
+		}
+		b.lockouts.remove(id)
+	}
+	return nil
+}
+
+// loginRateLimiter counts the successful logins of each principal, a CAM role,
+// to each role in fixed one-minute windows. Counts are kept in memory, per node.
+type loginRateLimiter struct {
+	lock    sync.Mutex
+	windows map[string]*loginCount
+}
+
+type loginCount struct {
+	start time.Time
+	count int
+}
+
+// maxTrackedLoginCounts is the number of counts above which expired ones are
+// dropped.
+const maxTrackedLoginCounts = 4096
+
+func newLoginRateLimiter() *loginRateLimiter {
+	return &loginRateLimiter{windows: make(map[string]*loginCount)}
+}
+
+// allow counts a login of key and reports whether it is within limit logins
+// in the current minute.
+func (l *loginRateLimiter) allow(key string, limit int, now time.Time) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if len(l.windows) > maxTrackedLoginCounts {
+		for k, c := range l.windows {
+			if now.Sub(c.start) >= time.Minute {
+				delete(l.windows, k)
+			}
+		}
+	}
+	c, ok := l.windows[key]
+	if !ok || now.Sub(c.start) >= time.Minute {
+		c = &loginCount{start: now}
+		l.windows[key] = c
+	}
+	if c.count >= limit {
+		return false
+	}
+	c.count++
+	return true
+}
+
+// peek reports whether a login of key would be allowed, without counting it.
+func (l *loginRateLimiter) peek(key string, limit int, now time.Time) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	c, ok := l.windows[key]
+	return !ok || now.Sub(c.start) >= time.Minute || c.count < limit
+}
+
+// checkLoginRate fails a login once the principal has logged in to the role
+// max_logins_per_minute times in the current minute. A dry run checks the
+// rate without counting the login.
+func (b *backend) checkLoginRate(role *roleEntry, roleName, principal string, dryRun bool) error {
+	if role.MaxLoginsPerMinute <= 0 || principal == "" {
+		return nil
+	}
+	key, now := roleName+"\x00"+principal, time.Now()
+	var allowed bool
+	if dryRun {
+		allowed = b.loginRates.peek(key, role.MaxLoginsPerMinute, now)
+	} else {
+		allowed = b.loginRates.allow(key, role.MaxLoginsPerMinute, now)
+	}
+	if !allowed {
+		return newLoginError(errCodeRateLimited, "too many logins to the role, try again later",
+			fmt.Errorf("%s exceeded %d logins per minute to role %s", principal, role.MaxLoginsPerMinute, roleName))
+	}
+	return nil
+}
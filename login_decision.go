@@ -0,0 +1,71 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	decisionAllowed = "allowed"
+	decisionDenied  = "denied"
+	decisionError   = "error"
+)
+
+// logDecision logs the outcome of a login for later investigation. Allowed
+// logins are logged at info, denied ones at warn and internal failures at
+// error. Only what was learned about the caller is logged, never the
+// credentials it presented.
+func (b *backend) logDecision(ctx context.Context, req *logical.Request,
+	attempt *loginAttempt, start time.Time, err error) {
+	config, cfgErr := b.getLoggingConfig(ctx, req.Storage)
+	if cfgErr != nil {
+		b.Logger().Error("unable to read the logging config", "error", cfgErr)
+		config = &loggingConfig{DecisionLogLevel: defaultDecisionLogLevel}
+	}
+
+	level, decision := hclog.Info, decisionAllowed
+	var loginErr *loginError
+	switch {
+	case err == nil:
+	case errors.As(err, &loginErr):
+		level, decision = hclog.Warn, decisionDenied
+	default:
+		level, decision = hclog.Error, decisionError
+	}
+	if level < config.level() {
+		return
+	}
+
+	args := []interface{}{
+		"decision", decision,
+		"role", attempt.roleName,
+		"requested_role", attempt.requestedRole,
+		"arn", attempt.arn,
+		"account", attempt.account,
+		"identity_type", attempt.identityType,
+		"source_ip", sourceIP(req),
+		"sts_region", attempt.stsRegion,
+		"request_id", req.ID,
+		"duration", time.Since(start).String(),
+	}
+	if err != nil {
+		args = append(args,
+			"constraint", attempt.lastStage,
+			"code", loginErrorCode(err),
+			"error", err.Error(),
+		)
+	}
+	b.Logger().Log(level, "login decision", args...)
+}
+
+// sourceIP returns the remote address of the request, if Vault passed it on.
+func sourceIP(req *logical.Request) string {
+	if req.Connection == nil {
+		return ""
+	}
+	return req.Connection.RemoteAddr
+}
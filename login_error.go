@@ -0,0 +1,129 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault-plugin-auth-tencentcloud/clients"
+	"github.com/hashicorp/vault/sdk/logical"
+	tcerr "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/errors"
+)
+
+// Login failure codes returned to clients. They are part of the API: clients
+// rely on them to tell failures they can fix apart from ones worth retrying.
+const (
+	errCodeInvalidRequest          = "invalid_request"
+	errCodeInvalidCredentials      = "invalid_credentials"
+	errCodeExpiredToken            = "expired_token"
+	errCodeUnsupportedIdentityType = "unsupported_identity_type"
+	errCodeRoleNotFound            = "role_not_found"
+	errCodeRoleDisabled            = "role_disabled"
+	errCodeARNMismatch             = "arn_mismatch"
+	errCodeCIDRDenied              = "cidr_denied"
+	errCodeConditionFailed         = "condition_failed"
+	errCodeLoginTimeDenied         = "login_time_denied"
+	errCodeLockedOut               = "locked_out"
+	errCodeRateLimited             = "rate_limited"
+	errCodeSourceDenied            = "source_denied"
+	errCodeUpstreamUnavailable     = "upstream_unavailable"
+	errCodeUpstreamError           = "upstream_error"
+)
+
+// loginErrorStatus maps failure codes to the HTTP status returned with them.
+var loginErrorStatus = map[string]int{
+	errCodeInvalidRequest:          http.StatusBadRequest,
+	errCodeInvalidCredentials:      http.StatusUnauthorized,
+	errCodeExpiredToken:            http.StatusUnauthorized,
+	errCodeUnsupportedIdentityType: http.StatusBadRequest,
+	errCodeRoleNotFound:            http.StatusBadRequest,
+	errCodeRoleDisabled:            http.StatusForbidden,
+	errCodeARNMismatch:             http.StatusForbidden,
+	errCodeCIDRDenied:              http.StatusForbidden,
+	errCodeConditionFailed:         http.StatusForbidden,
+	errCodeLoginTimeDenied:         http.StatusForbidden,
+	errCodeLockedOut:               http.StatusForbidden,
+	errCodeRateLimited:             http.StatusTooManyRequests,
+	errCodeSourceDenied:            http.StatusForbidden,
+	errCodeUpstreamUnavailable:     http.StatusServiceUnavailable,
+	errCodeUpstreamError:           http.StatusBadGateway,
+}
+
+// loginError is a login failure the client can act on. Only the code and
+// message are returned to the client; the underlying error, which may hold
+// upstream request ids, is only logged.
+type loginError struct {
+	code    string
+	message string
+	err     error
+}
+
+func newLoginError(code, message string, err error) *loginError {
+	return &loginError{code: code, message: message, err: err}
+}
+
+func (e *loginError) Error() string {
+	if e.err == nil {
+		return e.code + ": " + e.message
+	}
+	return e.code + ": " + e.message + ": " + e.err.Error()
+}
+
+func (e *loginError) Unwrap() error {
+	return e.err
+}
+
+// upstreamLoginError classifies a failed STS or CAM call. callerCreds tells
+// whether the call was signed with the caller's own credentials: the mount's
+// credentials being rejected is not the caller's failure, so it is reported
+// as an upstream error.
+func upstreamLoginError(service string, callerCreds bool, err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return newLoginError(errCodeUpstreamUnavailable, service+" is unavailable, retry later", err)
+	}
+	if errors.Is(err, clients.ErrNoValidCredentialsFound) {
+		if !callerCreds {
+			return newLoginError(errCodeUpstreamError, "no valid credentials are configured for "+service, err)
+		}
+		return newLoginError(errCodeInvalidCredentials, "no valid credentials were provided", err)
+	}
+	var sdkErr *tcerr.TencentCloudSDKError
+	if !errors.As(err, &sdkErr) {
+		return newLoginError(errCodeUpstreamError, service+" request failed", err)
+	}
+	code := sdkErr.GetCode()
+	switch {
+	case code == "AuthFailure.SignatureExpire":
+		// Requests are signed here, so a stale signature is this server's
+		// clock being off rather than the credentials expiring.
+		return newLoginError(errCodeUpstreamError, service+" request failed", err)
+	case !callerCreds && (strings.HasPrefix(code, "AuthFailure") || code == "InvalidParameter.AccessKeyNotSupport"):
+		return newLoginError(errCodeUpstreamError, service+" rejected the configured credentials", err)
+	case code == "AuthFailure.TokenFailure":
+		// The token of temporary credentials is rejected once they expire.
+		return newLoginError(errCodeExpiredToken, "the credentials have expired, obtain new ones and retry", err)
+	case strings.HasPrefix(code, "AuthFailure") || code == "InvalidParameter.AccessKeyNotSupport":
+		return newLoginError(errCodeInvalidCredentials, "the credentials were rejected by "+service, err)
+	case clients.IsRetryable(err) || clients.IsRegionalFailure(err):
+		return newLoginError(errCodeUpstreamUnavailable, service+" is unavailable, retry later", err)
+	case clients.IsRoleNotFound(err):
+		return newLoginError(errCodeRoleNotFound, "the caller's CAM role does not exist", err)
+	}
+	return newLoginError(errCodeUpstreamError, service+" request failed", err)
+}
+
+// loginErrorResponse turns a login failure into a response. Failures that are
+// not a loginError are returned as they are.
+func (b *backend) loginErrorResponse(req *logical.Request, err error) (*logical.Response, error) {
+	var loginErr *loginError
+	if !errors.As(err, &loginErr) {
+		return nil, err
+	}
+	resp := logical.ErrorResponse("%s: %s", loginErr.code, loginErr.message)
+	resp.Data["error_code"] = loginErr.code
+	return logical.RespondWithStatusCode(resp, req, loginErrorStatus[loginErr.code])
+}
@@ -0,0 +1,50 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"errors"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+)
+
+// unknownLabel stands for a label value that is not known, e.g. the role of a
+// login that failed before its role was read. Unverified input is never used
+// as a label, so failed logins cannot blow up the metrics' cardinality.
+const unknownLabel = "unknown"
+
+// errCodeInternal labels failures that are not a loginError.
+const errCodeInternal = "internal_error"
+
+// emitLoginMetrics records the outcome and latency of a login.
+func emitLoginMetrics(attempt *loginAttempt, start time.Time, err error) {
+	labels := []metrics.Label{
+		{Name: "role", Value: labelValue(attempt.roleName)},
+		{Name: "identity_type", Value: labelValue(attempt.identityType)},
+	}
+	metrics.IncrCounterWithLabels([]string{"tencentcloud", "login", "attempt"}, 1, labels)
+	metrics.MeasureSinceWithLabels([]string{"tencentcloud", "login", "attempt"}, start, labels)
+	if err == nil {
+		metrics.IncrCounterWithLabels([]string{"tencentcloud", "login", "success"}, 1, labels)
+		metrics.MeasureSinceWithLabels([]string{"tencentcloud", "login", "success"}, start, labels)
+		return
+	}
+	labels = append(labels, metrics.Label{Name: "code", Value: loginErrorCode(err)})
+	metrics.IncrCounterWithLabels([]string{"tencentcloud", "login", "failure"}, 1, labels)
+	metrics.MeasureSinceWithLabels([]string{"tencentcloud", "login", "failure"}, start, labels)
+}
+
+// loginErrorCode returns the failure code of a login error.
+func loginErrorCode(err error) string {
+	var loginErr *loginError
+	if errors.As(err, &loginErr) {
+		return loginErr.code
+	}
+	return errCodeInternal
+}
+
+func labelValue(value string) string {
+	if value == "" {
+		return unknownLabel
+	}
+	return value
+}
@@ -0,0 +1,329 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	notificationQueueSize   = 1024
+	notificationWorkers     = 4
+	notificationMaxAttempts = 4
+	notificationRetryDelay  = time.Second
+	notificationTimeout     = 10 * time.Second
+
+	// Headers sent with each event.
+	eventHeader     = "X-Vault-TencentCloud-Event"
+	deliveryHeader  = "X-Vault-TencentCloud-Delivery"
+	signatureHeader = "X-Vault-TencentCloud-Signature"
+)
+
+// event is the JSON body POSTed to webhooks.
+type event struct {
+	ID   string                 `json:"id"`
+	Type string                 `json:"type"`
+	Time time.Time              `json:"time"`
+	Data map[string]interface{} `json:"data"`
+}
+
+// delivery is one event on its way to one webhook.
+type delivery struct {
+	webhook *webhookConfig
+	client  *http.Client
+	body    []byte
+	event   *event
+}
+
+// notifier delivers events to webhooks in the background. Events wait in a
+// bounded queue; when it is full new events are dropped rather than slowing
+// down requests.
+type notifier struct {
+	logger func() hclog.Logger
+	queue  chan *delivery
+
+	// retryDelay is the delay before the first retry. It doubles with each
+	// further retry.
+	retryDelay time.Duration
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stop      chan struct{}
+	workers   sync.WaitGroup
+
+	lock                 sync.Mutex
+	delivered            uint64
+	retries              uint64
+	dropped              uint64
+	deadLetters          uint64
+	deadLettersByWebhook map[string]uint64
+}
+
+// notifierStatus is a snapshot of the notifier's counters.
+type notifierStatus struct {
+	queueLength          int
+	queueCapacity        int
+	delivered            uint64
+	retries              uint64
+	dropped              uint64
+	deadLetters          uint64
+	deadLettersByWebhook map[string]uint64
+}
+
+func newNotifier(logger func() hclog.Logger) *notifier {
+	return &notifier{
+		logger:               logger,
+		queue:                make(chan *delivery, notificationQueueSize),
+		retryDelay:           notificationRetryDelay,
+		stop:                 make(chan struct{}),
+		deadLettersByWebhook: make(map[string]uint64),
+	}
+}
+
+// enqueue queues d without blocking, starting the workers on first use.
+func (n *notifier) enqueue(d *delivery) {
+	n.startOnce.Do(func() {
+		for i := 0; i < notificationWorkers; i++ {
+			n.workers.Add(1)
+			go n.work()
+		}
+	})
+	select {
+	case n.queue <- d:
+	default:
+		n.lock.Lock()
+		n.dropped++
+		n.lock.Unlock()
+		n.logger().Warn("notification queue is full, dropping event",
+			"webhook", d.webhook.Name, "type", d.event.Type, "id", d.event.ID)
+	}
+}
+
+// shutdown stops the workers. Queued events are not delivered.
+func (n *notifier) shutdown() {
+	n.stopOnce.Do(func() {
+		close(n.stop)
+	})
+	n.workers.Wait()
+}
+
+func (n *notifier) work() {
+	defer n.workers.Done()
+	for {
+		select {
+		case <-n.stop:
+			return
+		case d := <-n.queue:
+			n.deliver(d)
+		}
+	}
+}
+
+// deliver POSTs d, retrying failures with exponential backoff. Deliveries
+// that still fail are counted as dead letters.
+func (n *notifier) deliver(d *delivery) {
+	delay := n.retryDelay
+	var err error
+	for attempt := 1; attempt <= notificationMaxAttempts; attempt++ {
+		if attempt > 1 {
+			n.lock.Lock()
+			n.retries++
+			n.lock.Unlock()
+			select {
+			case <-n.stop:
+				return
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+		if err = n.post(d); err == nil {
+			n.lock.Lock()
+			n.delivered++
+			n.lock.Unlock()
+			return
+		}
+	}
+	n.lock.Lock()
+	n.deadLetters++
+	n.deadLettersByWebhook[d.webhook.Name]++
+	n.lock.Unlock()
+	n.logger().Warn("unable to deliver event", "webhook", d.webhook.Name,
+		"type", d.event.Type, "id", d.event.ID, "attempts", notificationMaxAttempts, "error", err)
+}
+
+func (n *notifier) post(d *delivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.webhook.URL, bytes.NewReader(d.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(eventHeader, d.event.Type)
+	req.Header.Set(deliveryHeader, d.event.ID)
+	req.Header.Set(signatureHeader, signEvent(d.webhook.Secret, d.body))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+func (n *notifier) status() *notifierStatus {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	byWebhook := make(map[string]uint64, len(n.deadLettersByWebhook))
+	for name, count := range n.deadLettersByWebhook {
+		byWebhook[name] = count
+	}
+	return &notifierStatus{
+		queueLength:          len(n.queue),
+		queueCapacity:        cap(n.queue),
+		delivered:            n.delivered,
+		retries:              n.retries,
+		dropped:              n.dropped,
+		deadLetters:          n.deadLetters,
+		deadLettersByWebhook: byWebhook,
+	}
+}
+
+// signEvent returns the value of the signature header for body: "sha256="
+// followed by the hex HMAC-SHA256 of body keyed with secret.
+func signEvent(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// notify queues an event of type eventType for every webhook that wants it.
+// role is the role the event is about, if any. Failures are logged and never
+// fail the request that caused the event.
+func (b *backend) notify(ctx context.Context, s logical.Storage, eventType, role string, data map[string]interface{}) {
+	webhooks, err := b.getWebhooks(ctx, s)
+	if err != nil {
+		b.Logger().Error("unable to read the webhooks", "error", err)
+		return
+	}
+	var targets []*webhookConfig
+	for _, webhook := range webhooks {
+		if webhook.wants(eventType, role) {
+			targets = append(targets, webhook)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	client, err := b.getHTTPClient(ctx, s)
+	if err != nil {
+		b.Logger().Error("unable to build the webhook http client", "error", err)
+		return
+	}
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		b.Logger().Error("unable to generate an event id", "error", err)
+		return
+	}
+	e := &event{
+		ID:   id,
+		Type: eventType,
+		Time: time.Now().UTC(),
+		Data: data,
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		b.Logger().Error("unable to encode an event", "type", eventType, "error", err)
+		return
+	}
+	for _, webhook := range targets {
+		b.notifier.enqueue(&delivery{
+			webhook: webhook,
+			client:  client,
+			body:    body,
+			event:   e,
+		})
+	}
+}
+
+// notifyLogin queues a login_success or login_denied event. Internal failures
+// are not denials and are not notified.
+func (b *backend) notifyLogin(ctx context.Context, req *logical.Request, attempt *loginAttempt, err error) {
+	eventType := eventLoginSuccess
+	data := map[string]interface{}{
+		"role":           attempt.roleName,
+		"requested_role": attempt.requestedRole,
+		"arn":            attempt.arn,
+		"account":        attempt.account,
+		"source_ip":      sourceIP(req),
+		"request_id":     req.ID,
+	}
+	if err != nil {
+		if loginErrorCode(err) == errCodeInternal {
+			return
+		}
+		eventType = eventLoginDenied
+		data["code"] = loginErrorCode(err)
+		data["constraint"] = attempt.lastStage
+	}
+	role := attempt.roleName
+	if role == "" {
+		role = attempt.requestedRole
+	}
+	b.notify(ctx, req.Storage, eventType, role, data)
+}
+
+// notifyChanges wraps the create, update and delete callbacks of p to queue
+// an event of type eventType after each successful change.
+func (b *backend) notifyChanges(eventType string, p *framework.Path) *framework.Path {
+	for op, handler := range p.Operations {
+		if op != logical.CreateOperation && op != logical.UpdateOperation && op != logical.DeleteOperation {
+			continue
+		}
+		pathOp, ok := handler.(*framework.PathOperation)
+		if !ok {
+			continue
+		}
+		callback := pathOp.Callback
+		pathOp.Callback = func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+			resp, err := callback(ctx, req, data)
+			if err != nil || resp.IsError() {
+				return resp, err
+			}
+			role := ""
+			if eventType == eventRoleChanged {
+				role = strings.SplitN(strings.TrimPrefix(req.Path, rolePath), "/", 2)[0]
+			}
+			details := map[string]interface{}{
+				"path":       req.Path,
+				"operation":  string(req.Operation),
+				"request_id": req.ID,
+			}
+			if role != "" {
+				details["role"] = role
+			}
+			b.notify(ctx, req.Storage, eventType, role, details)
+			return resp, err
+		}
+	}
+	return p
+}
@@ -0,0 +1,432 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault-plugin-auth-tencentcloud/clients"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+)
+
+const (
+	configAccountStoragePrefix = "config/account/"
+	accountId                  = "account_id"
+	secretId                   = "secret_id"
+	secretKey                  = "secret_key"
+	roleArn                    = "role_arn"
+	roleSessionName            = "role_session_name"
+
+	defaultRoleSessionName = "vault-auth-tencentcloud"
+
+	// legacyClientConfigStoragePath held the mount-wide credentials that
+	// config/account replaced.
+	legacyClientConfigStoragePath = "config/client"
+)
+
+// accountConfig holds the credentials used for server-side lookups in one account.
+type accountConfig struct {
+	AccountId       string `json:"account_id"`
+	SecretId        string `json:"secret_id"`
+	SecretKey       string `json:"secret_key"`
+	RoleArn         string `json:"role_arn"`
+	RoleSessionName string `json:"role_session_name"`
+}
+
+func pathConfigAccount(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: configAccountStoragePrefix + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeLowerCaseString,
+				Description: "Name of the account configuration.",
+			},
+			accountId: {
+				Type:        framework.TypeString,
+				Description: "UIN of the TencentCloud account these settings apply to.",
+			},
+			secretId: {
+				Type:        framework.TypeString,
+				Description: "Secret Id used to make TencentCloud API requests in the account.",
+			},
+			secretKey: {
+				Type:        framework.TypeString,
+				Description: "Secret Key used to make TencentCloud API requests in the account.",
+			},
+			roleArn: {
+				Type:        framework.TypeString,
+				Description: "ARN of a CAM role to assume before making TencentCloud API requests in the account.",
+			},
+			roleSessionName: {
+				Type:        framework.TypeString,
+				Description: "Session name used when assuming role_arn.",
+				Default:     defaultRoleSessionName,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.CreateOperation: &framework.PathOperation{
+				Callback: b.pathConfigAccountWrite,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathConfigAccountWrite,
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathConfigAccountRead,
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathConfigAccountDelete,
+			},
+		},
+		ExistenceCheck:  b.pathConfigAccountExistenceCheck,
+		HelpSynopsis:    pathConfigAccountHelpSyn,
+		HelpDescription: pathConfigAccountHelpDesc,
+	}
+}
+
+func pathListConfigAccounts(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: configAccountStoragePrefix + "?$",
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ListOperation: &framework.PathOperation{
+				Callback: b.pathConfigAccountList,
+			},
+		},
+		HelpSynopsis:    pathListConfigAccountsHelpSyn,
+		HelpDescription: pathListConfigAccountsHelpDesc,
+	}
+}
+
+// pathLegacyConfigClient only deletes config/client, so that it can be
+// cleaned up once its credentials have moved to config/account.
+func pathLegacyConfigClient(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: legacyClientConfigStoragePath,
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathLegacyConfigClientDelete,
+			},
+		},
+		HelpSynopsis:    pathLegacyConfigClientHelpSyn,
+		HelpDescription: pathLegacyConfigClientHelpDesc,
+	}
+}
+
+// pathConfigAccountWrite
+func (b *backend) pathConfigAccountWrite(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.accountsLock.Lock()
+	defer b.accountsLock.Unlock()
+	name := data.Get("name").(string)
+	config, err := readAccountConfig(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		if req.Operation == logical.UpdateOperation {
+			return nil, fmt.Errorf("no account config found to update for %s", name)
+		}
+		config = &accountConfig{
+			RoleSessionName: defaultRoleSessionName,
+		}
+	}
+
+	if raw, ok := data.GetOk(accountId); ok {
+		config.AccountId = strings.TrimSpace(raw.(string))
+	}
+	if raw, ok := data.GetOk(secretId); ok {
+		config.SecretId = raw.(string)
+	}
+	if raw, ok := data.GetOk(secretKey); ok {
+		config.SecretKey = raw.(string)
+	}
+	if raw, ok := data.GetOk(roleArn); ok {
+		config.RoleArn = raw.(string)
+	}
+	if raw, ok := data.GetOk(roleSessionName); ok {
+		config.RoleSessionName = raw.(string)
+	}
+
+	if config.AccountId == "" {
+		return logical.ErrorResponse("account_id is required"), nil
+	}
+	if (config.SecretId == "") != (config.SecretKey == "") {
+		return logical.ErrorResponse("secret_id and secret_key must be set together"), nil
+	}
+	if config.RoleArn != "" {
+		parsed, err := parseARN(config.RoleArn)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("unable to parse role_arn %s: %s", config.RoleArn, err)), nil
+		}
+		if parsed.Type != arnRoleType {
+			return logical.ErrorResponse("role_arn must be a role arn"), nil
+		}
+		if config.RoleSessionName == "" {
+			return logical.ErrorResponse("role_session_name must not be empty when role_arn is set"), nil
+		}
+	}
+
+	existing, err := findAccountConfig(ctx, req.Storage, config.AccountId)
+	if err != nil {
+		return nil, err
+	}
+	if existing != "" && existing != name {
+		return logical.ErrorResponse(fmt.Sprintf(
+			"account %s is already configured by %s", config.AccountId, existing)), nil
+	}
+
+	if err := writeAccountConfig(ctx, req.Storage, name, config); err != nil {
+		return nil, err
+	}
+	b.clients.reset()
+	return nil, nil
+}
+
+// pathConfigAccountRead
+func (b *backend) pathConfigAccountRead(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := readAccountConfig(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			accountId:       config.AccountId,
+			secretId:        config.SecretId,
+			roleArn:         config.RoleArn,
+			roleSessionName: config.RoleSessionName,
+		},
+	}, nil
+}
+
+// pathConfigAccountDelete
+func (b *backend) pathConfigAccountDelete(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.accountsLock.Lock()
+	defer b.accountsLock.Unlock()
+	if err := req.Storage.Delete(ctx, configAccountStoragePrefix+data.Get("name").(string)); err != nil {
+		return nil, err
+	}
+	b.clients.reset()
+	return nil, nil
+}
+
+// pathLegacyConfigClientDelete
+func (b *backend) pathLegacyConfigClientDelete(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete(ctx, legacyClientConfigStoragePath); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// pathConfigAccountList
+func (b *backend) pathConfigAccountList(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	names, err := req.Storage.List(ctx, configAccountStoragePrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(names), nil
+}
+
+// pathConfigAccountExistenceCheck
+func (b *backend) pathConfigAccountExistenceCheck(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (bool, error) {
+	config, err := readAccountConfig(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return config != nil, nil
+}
+
+func readAccountConfig(ctx context.Context, s logical.Storage, name string) (*accountConfig, error) {
+	entry, err := s.Get(ctx, configAccountStoragePrefix+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	config := &accountConfig{}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func writeAccountConfig(ctx context.Context, s logical.Storage, name string, config *accountConfig) error {
+	entry, err := logical.StorageEntryJSON(configAccountStoragePrefix+name, config)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+// findAccountConfig returns the name of the entry configured for the account uin,
+// or an empty string if there is none.
+func findAccountConfig(ctx context.Context, s logical.Storage, uin string) (string, error) {
+	if uin == "" {
+		return "", errors.New("missing account id")
+	}
+	names, err := s.List(ctx, configAccountStoragePrefix)
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		config, err := readAccountConfig(ctx, s, name)
+		if err != nil {
+			return "", err
+		}
+		if config != nil && config.AccountId == uin {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// readAccountConfigFor returns the entry configured for the account uin, if any.
+func readAccountConfigFor(ctx context.Context, s logical.Storage, uin string) (*accountConfig, error) {
+	name, err := findAccountConfig(ctx, s, uin)
+	if err != nil || name == "" {
+		return nil, err
+	}
+	return readAccountConfig(ctx, s, name)
+}
+
+// warnLegacyClientConfig logs a warning if config/client, which is no longer
+// read, is still in storage. It cannot be migrated to config/account
+// because it does not say which account its credentials belong to.
+func (b *backend) warnLegacyClientConfig(ctx context.Context, s logical.Storage) error {
+	entry, err := s.Get(ctx, legacyClientConfigStoragePath)
+	if err != nil || entry == nil {
+		return err
+	}
+	b.Logger().Warn("config/client is no longer used and has been ignored; write its credentials to " +
+		"config/account/<name> with the account_id they belong to, then delete config/client")
+	return nil
+}
+
+// credentials resolves the credentials used for server-side lookups in the account,
+// and when they expire. The zero time means they do not expire.
+func (c *accountConfig) credentials(ctx context.Context, factory clients.Factory,
+	config *clients.Config) (common.CredentialIface, time.Time, error) {
+	creds, err := clients.ChainedCredsToCli(c.SecretId, c.SecretKey, "")
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if c.RoleArn == "" {
+		return creds, time.Time{}, nil
+	}
+	stsClient, err := factory.NewSTSClient(creds, config)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return stsClient.AssumeRole(ctx, c.RoleArn, c.RoleSessionName)
+}
+
+// camClientForAccount returns a CAM client for server-side lookups in the account uin.
+// The caller's credentials are used when no account config matches, which is
+// reported by the returned bool.
+func (b *backend) camClientForAccount(ctx context.Context, s logical.Storage, api *apiConfig,
+	uin, sId, sKey, token string) (clients.CAMAPI, bool, error) {
+	client, err := b.accountCAMClient(ctx, s, api, uin)
+	if err != nil || client != nil {
+		return client, false, err
+	}
+	client, err = b.callerCAMClient(api, sId, sKey, token)
+	return client, true, err
+}
+
+// accountCAMClient returns a CAM client built from the account config matching uin,
+// or nil if there is none. Clients are cached until a config changes or their
+// credentials expire.
+func (b *backend) accountCAMClient(ctx context.Context, s logical.Storage, api *apiConfig,
+	uin string) (clients.CAMAPI, error) {
+	gen := b.clients.generation()
+	name, err := b.accountConfigName(ctx, s, uin)
+	if err != nil || name == "" {
+		return nil, err
+	}
+
+	key := name + "/" + api.endpoints.DefaultRegion
+	if client := b.clients.cam(key); client != nil {
+		return client, nil
+	}
+	config, err := readAccountConfig(ctx, s, name)
+	if err != nil || config == nil {
+		return nil, err
+	}
+	creds, expiration, err := config.credentials(ctx, b.clientFactory, api.stsConfig(""))
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf(
+			"unable to resolve credentials for account %s due to {{err}}", uin), err)
+	}
+	client, err := b.clientFactory.NewCAMClient(creds, api.camConfig())
+	if err != nil {
+		return nil, err
+	}
+	b.clients.setCAM(gen, key, client, expiration)
+	return client, nil
+}
+
+// accountConfigName returns the name of the account config matching uin, or
+// an empty string if there is none, remembering it until a config changes.
+func (b *backend) accountConfigName(ctx context.Context, s logical.Storage, uin string) (string, error) {
+	gen := b.clients.generation()
+	if name, ok := b.clients.account(uin); ok {
+		return name, nil
+	}
+	name, err := findAccountConfig(ctx, s, uin)
+	if err != nil {
+		return "", err
+	}
+	b.clients.setAccount(gen, uin, name)
+	return name, nil
+}
+
+// callerCAMClient returns a CAM client using the caller's own credentials.
+func (b *backend) callerCAMClient(api *apiConfig, sId, sKey, token string) (clients.CAMAPI, error) {
+	creds, err := clients.ChainedCredsToCli(sId, sKey, token)
+	if err != nil {
+		return nil, err
+	}
+	return b.clientFactory.NewCAMClient(creds, api.camConfig())
+}
+
+const (
+	pathConfigAccountHelpSyn = `
+    Configure the credentials used to make TencentCloud API requests in one account.
+    `
+	pathConfigAccountHelpDesc = `
+    Server-side lookups, such as resolving the caller's CAM role, are made in the
+    caller's account. Each entry binds an account UIN to either a secret id and key,
+    or a CAM role to assume. When role_arn is set, the secret id and key, or the
+    credentials found in the environment or the CVM role, are used to assume it.
+    Logins from an account without an entry resolve the CAM role with the caller's
+    own credentials. Lookups made without a caller, on renewal or in a dry run,
+    need an entry for the account.
+    `
+	pathLegacyConfigClientHelpSyn = `
+    Delete the credentials of earlier versions, which config/account replaced.
+    `
+	pathLegacyConfigClientHelpDesc = `
+    config/client is no longer read. Move its credentials to a config/account
+    entry for the account they belong to, then delete it here.
+    `
+	pathListConfigAccountsHelpSyn = `
+    Lists the configured accounts.
+    `
+	pathListConfigAccountsHelpDesc = `
+    Account configurations will be listed by their respective names.
+    `
+)
@@ -0,0 +1,172 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	configCacheStoragePath = "config/cache"
+	roleCacheTTL           = "role_cache_ttl"
+	roleCacheNegativeTTL   = "role_cache_negative_ttl"
+	roleCacheSize          = "role_cache_size"
+
+	defaultRoleCacheTTL         = 5 * time.Minute
+	defaultRoleCacheNegativeTTL = 30 * time.Second
+	defaultRoleCacheSize        = 1024
+)
+
+// cacheConfig holds the settings of the CAM RoleId to RoleName cache.
+type cacheConfig struct {
+	RoleCacheTTL         time.Duration `json:"role_cache_ttl"`
+	RoleCacheNegativeTTL time.Duration `json:"role_cache_negative_ttl"`
+	RoleCacheSize        int           `json:"role_cache_size"`
+}
+
+func pathConfigCache(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: configCacheStoragePath,
+		Fields: map[string]*framework.FieldSchema{
+			roleCacheTTL: {
+				Type:        framework.TypeDurationSecond,
+				Description: "How long a resolved CAM role name is cached. 0 disables caching.",
+				Default:     int(defaultRoleCacheTTL.Seconds()),
+			},
+			roleCacheNegativeTTL: {
+				Type:        framework.TypeDurationSecond,
+				Description: "How long a RoleId that does not exist is cached. 0 disables negative caching.",
+				Default:     int(defaultRoleCacheNegativeTTL.Seconds()),
+			},
+			roleCacheSize: {
+				Type:        framework.TypeInt,
+				Description: "Maximum number of cached RoleIds. The least recently used are evicted first.",
+				Default:     defaultRoleCacheSize,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.CreateOperation: &framework.PathOperation{
+				Callback: b.pathConfigCacheWrite,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathConfigCacheWrite,
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathConfigCacheRead,
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathConfigCacheDelete,
+			},
+		},
+		ExistenceCheck:  b.pathConfigCacheExistenceCheck,
+		HelpSynopsis:    pathConfigCacheHelpSyn,
+		HelpDescription: pathConfigCacheHelpDesc,
+	}
+}
+
+// pathConfigCacheWrite
+func (b *backend) pathConfigCacheWrite(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := readCacheConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if raw, ok := data.GetOk(roleCacheTTL); ok {
+		config.RoleCacheTTL = time.Duration(raw.(int)) * time.Second
+	}
+	if raw, ok := data.GetOk(roleCacheNegativeTTL); ok {
+		config.RoleCacheNegativeTTL = time.Duration(raw.(int)) * time.Second
+	}
+	if raw, ok := data.GetOk(roleCacheSize); ok {
+		config.RoleCacheSize = raw.(int)
+	}
+
+	if config.RoleCacheTTL < 0 || config.RoleCacheNegativeTTL < 0 {
+		return logical.ErrorResponse("cache ttls must not be negative"), nil
+	}
+	if config.RoleCacheSize <= 0 {
+		return logical.ErrorResponse("role_cache_size must be positive"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(configCacheStoragePath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+	b.resetRoleNameCache()
+	return nil, nil
+}
+
+// pathConfigCacheRead
+func (b *backend) pathConfigCacheRead(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := readCacheConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			roleCacheTTL:         int64(config.RoleCacheTTL.Seconds()),
+			roleCacheNegativeTTL: int64(config.RoleCacheNegativeTTL.Seconds()),
+			roleCacheSize:        config.RoleCacheSize,
+		},
+	}, nil
+}
+
+// pathConfigCacheDelete
+func (b *backend) pathConfigCacheDelete(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete(ctx, configCacheStoragePath); err != nil {
+		return nil, err
+	}
+	b.resetRoleNameCache()
+	return nil, nil
+}
+
+// pathConfigCacheExistenceCheck
+func (b *backend) pathConfigCacheExistenceCheck(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (bool, error) {
+	entry, err := req.Storage.Get(ctx, configCacheStoragePath)
+	if err != nil {
+		return false, err
+	}
+	return entry != nil, nil
+}
+
+// readCacheConfig returns the stored cache config, or the defaults if none is stored.
+func readCacheConfig(ctx context.Context, s logical.Storage) (*cacheConfig, error) {
+	config := &cacheConfig{
+		RoleCacheTTL:         defaultRoleCacheTTL,
+		RoleCacheNegativeTTL: defaultRoleCacheNegativeTTL,
+		RoleCacheSize:        defaultRoleCacheSize,
+	}
+	entry, err := s.Get(ctx, configCacheStoragePath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return config, nil
+	}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+const (
+	pathConfigCacheHelpSyn = `
+    Configure the cache of CAM role names.
+    `
+	pathConfigCacheHelpDesc = `
+    Every login resolves the RoleId of the caller's assumed role to a CAM role
+    name. Resolved names are cached in memory for role_cache_ttl, RoleIds that
+    do not exist for role_cache_negative_ttl, and concurrent lookups of the
+    same RoleId share a single CAM call, unless they are made with different
+    callers' credentials.
+    `
+)
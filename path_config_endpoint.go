@@ -0,0 +1,236 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault-plugin-auth-tencentcloud/clients"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	configEndpointStoragePath = "config/endpoint"
+	defaultRegion             = "default_region"
+	domain                    = "domain"
+	stsEndpoint               = "sts_endpoint"
+	camEndpoint               = "cam_endpoint"
+	usePrivateEndpoint        = "use_private_endpoint"
+	stsFallbackRegions        = "sts_fallback_regions"
+)
+
+// endpointConfig holds the mount-wide settings that decide where TencentCloud API requests go.
+type endpointConfig struct {
+	DefaultRegion      string   `json:"default_region"`
+	Domain             string   `json:"domain"`
+	STSEndpoint        string   `json:"sts_endpoint"`
+	CAMEndpoint        string   `json:"cam_endpoint"`
+	UsePrivateEndpoint bool     `json:"use_private_endpoint"`
+	STSFallbackRegions []string `json:"sts_fallback_regions"`
+}
+
+func pathConfigEndpoint(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: configEndpointStoragePath,
+		Fields: map[string]*framework.FieldSchema{
+			defaultRegion: {
+				Type:        framework.TypeString,
+				Description: "Region used when the login request does not specify one.",
+				Default:     clients.DefaultRegion,
+			},
+			domain: {
+				Type: framework.TypeString,
+				Description: `API root domain. Use tencentcloudapi.com for the China site and
+intl.tencentcloudapi.com for the international site.`,
+				Default: clients.DefaultDomain,
+			},
+			stsEndpoint: {
+				Type:        framework.TypeString,
+				Description: "Overrides the STS endpoint. May be a host or a URL with a scheme.",
+			},
+			camEndpoint: {
+				Type:        framework.TypeString,
+				Description: "Overrides the CAM endpoint. May be a host or a URL with a scheme.",
+			},
+			usePrivateEndpoint: {
+				Type:        framework.TypeBool,
+				Description: "Use the private-network endpoints, reachable from inside TencentCloud without internet egress.",
+			},
+			stsFallbackRegions: {
+				Type: framework.TypeCommaStringSlice,
+				Description: `Ordered list of regions tried for GetCallerIdentity when the requested
+region fails with a network or server error.`,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.CreateOperation: &framework.PathOperation{
+				Callback: b.pathConfigEndpointWrite,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathConfigEndpointWrite,
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathConfigEndpointRead,
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathConfigEndpointDelete,
+			},
+		},
+		ExistenceCheck:  b.pathConfigEndpointExistenceCheck,
+		HelpSynopsis:    pathConfigEndpointHelpSyn,
+		HelpDescription: pathConfigEndpointHelpDesc,
+	}
+}
+
+// pathConfigEndpointWrite
+func (b *backend) pathConfigEndpointWrite(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := readEndpointConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if raw, ok := data.GetOk(defaultRegion); ok {
+		config.DefaultRegion = strings.TrimSpace(raw.(string))
+	}
+	if raw, ok := data.GetOk(domain); ok {
+		config.Domain = strings.TrimSpace(raw.(string))
+	}
+	if raw, ok := data.GetOk(stsEndpoint); ok {
+		config.STSEndpoint = strings.TrimSpace(raw.(string))
+	}
+	if raw, ok := data.GetOk(camEndpoint); ok {
+		config.CAMEndpoint = strings.TrimSpace(raw.(string))
+	}
+	if raw, ok := data.GetOk(usePrivateEndpoint); ok {
+		config.UsePrivateEndpoint = raw.(bool)
+	}
+	if raw, ok := data.GetOk(stsFallbackRegions); ok {
+		config.STSFallbackRegions = strutil.RemoveDuplicatesStable(raw.([]string), false)
+	}
+
+	if config.DefaultRegion == "" {
+		return logical.ErrorResponse("default_region must not be empty"), nil
+	}
+	if config.Domain == "" {
+		return logical.ErrorResponse("domain must not be empty"), nil
+	}
+	for field, endpoint := range map[string]string{
+		stsEndpoint: config.STSEndpoint,
+		camEndpoint: config.CAMEndpoint,
+	} {
+		if err := validateEndpoint(endpoint); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid %s: %s", field, err)), nil
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON(configEndpointStoragePath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+	b.clients.reset()
+	return nil, nil
+}
+
+// pathConfigEndpointRead
+func (b *backend) pathConfigEndpointRead(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := readEndpointConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			defaultRegion:      config.DefaultRegion,
+			domain:             config.Domain,
+			stsEndpoint:        config.STSEndpoint,
+			camEndpoint:        config.CAMEndpoint,
+			usePrivateEndpoint: config.UsePrivateEndpoint,
+			stsFallbackRegions: config.STSFallbackRegions,
+		},
+	}, nil
+}
+
+// pathConfigEndpointDelete
+func (b *backend) pathConfigEndpointDelete(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete(ctx, configEndpointStoragePath); err != nil {
+		return nil, err
+	}
+	b.clients.reset()
+	return nil, nil
+}
+
+// pathConfigEndpointExistenceCheck
+func (b *backend) pathConfigEndpointExistenceCheck(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (bool, error) {
+	entry, err := req.Storage.Get(ctx, configEndpointStoragePath)
+	if err != nil {
+		return false, err
+	}
+	return entry != nil, nil
+}
+
+// readEndpointConfig returns the stored endpoint config, or the defaults if none is stored.
+func readEndpointConfig(ctx context.Context, s logical.Storage) (*endpointConfig, error) {
+	config := &endpointConfig{
+		DefaultRegion: clients.DefaultRegion,
+		Domain:        clients.DefaultDomain,
+	}
+	entry, err := s.Get(ctx, configEndpointStoragePath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return config, nil
+	}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// stsRegions returns the regions tried for GetCallerIdentity, in order.
+func (c *endpointConfig) stsRegions(region string) []string {
+	if region == "" {
+		region = c.DefaultRegion
+	}
+	return strutil.RemoveDuplicatesStable(append([]string{region}, c.STSFallbackRegions...), false)
+}
+
+// validateEndpoint checks that an endpoint override is a host or an http(s) URL.
+func validateEndpoint(endpoint string) error {
+	if endpoint == "" || !strings.Contains(endpoint, "://") {
+		return nil
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("missing host")
+	}
+	return nil
+}
+
+const (
+	pathConfigEndpointHelpSyn = `
+    Configure where the backend sends TencentCloud API requests.
+    `
+	pathConfigEndpointHelpDesc = `
+    Sets the region used when a login request does not specify one, the API
+    domain of the China or international site, per-service endpoint overrides,
+    and whether to use the private-network endpoints. Vault servers without
+    internet egress should enable use_private_endpoint. When STS fails in the
+    requested region, sts_fallback_regions are tried in order.
+    `
+)
@@ -0,0 +1,193 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	configGuardStoragePath = "config/guard"
+	allowedSourceCIDRs     = "allowed_source_cidrs"
+	sourceRateLimit        = "source_rate_limit"
+	sourceRateBurst        = "source_rate_burst"
+)
+
+// guardConfig holds the settings of the checks made before a login calls
+// TencentCloud.
+type guardConfig struct {
+	// AllowedSourceCIDRs, if set, are the only addresses logins are accepted from.
+	AllowedSourceCIDRs []string `json:"allowed_source_cidrs"`
+	// SourceRateLimit is the number of logins per second accepted from each
+	// source address, with bursts of SourceRateBurst. 0 is unlimited.
+	SourceRateLimit float64 `json:"source_rate_limit"`
+	SourceRateBurst int     `json:"source_rate_burst"`
+}
+
+// burst returns SourceRateBurst, defaulting to the rate rounded up.
+func (c *guardConfig) burst() int {
+	if c.SourceRateBurst > 0 {
+		return c.SourceRateBurst
+	}
+	burst := int(c.SourceRateLimit)
+	if float64(burst) < c.SourceRateLimit {
+		burst++
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return burst
+}
+
+func pathConfigGuard(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: configGuardStoragePath,
+		Fields: map[string]*framework.FieldSchema{
+			allowedSourceCIDRs: {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "CIDR blocks logins are accepted from. If empty, logins are accepted from any address.",
+			},
+			sourceRateLimit: {
+				Type:        framework.TypeFloat,
+				Description: "Logins per second accepted from each source address. 0 is unlimited.",
+			},
+			sourceRateBurst: {
+				Type:        framework.TypeInt,
+				Description: "Logins accepted at once from each source address. Defaults to source_rate_limit rounded up.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.CreateOperation: &framework.PathOperation{
+				Callback: b.pathConfigGuardWrite,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathConfigGuardWrite,
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathConfigGuardRead,
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathConfigGuardDelete,
+			},
+		},
+		ExistenceCheck:  b.pathConfigGuardExistenceCheck,
+		HelpSynopsis:    pathConfigGuardHelpSyn,
+		HelpDescription: pathConfigGuardHelpDesc,
+	}
+}
+
+// pathConfigGuardWrite
+func (b *backend) pathConfigGuardWrite(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := readGuardConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if raw, ok := data.GetOk(allowedSourceCIDRs); ok {
+		cidrs := raw.([]string)
+		for i, cidr := range cidrs {
+			_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+			if err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("invalid %s entry %q", allowedSourceCIDRs, cidr)), nil
+			}
+			cidrs[i] = network.String()
+		}
+		config.AllowedSourceCIDRs = cidrs
+	}
+	if raw, ok := data.GetOk(sourceRateLimit); ok {
+		config.SourceRateLimit = raw.(float64)
+	}
+	if raw, ok := data.GetOk(sourceRateBurst); ok {
+		config.SourceRateBurst = raw.(int)
+	}
+	if config.SourceRateLimit < 0 || config.SourceRateBurst < 0 {
+		return logical.ErrorResponse(sourceRateLimit + " and " + sourceRateBurst + " must not be negative"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(configGuardStoragePath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+	b.resetGuard()
+	return nil, nil
+}
+
+// pathConfigGuardRead
+func (b *backend) pathConfigGuardRead(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := readGuardConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	cidrs := config.AllowedSourceCIDRs
+	if cidrs == nil {
+		cidrs = []string{}
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			allowedSourceCIDRs: cidrs,
+			sourceRateLimit:    config.SourceRateLimit,
+			sourceRateBurst:    config.burst(),
+		},
+	}, nil
+}
+
+// pathConfigGuardDelete
+func (b *backend) pathConfigGuardDelete(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete(ctx, configGuardStoragePath); err != nil {
+		return nil, err
+	}
+	b.resetGuard()
+	return nil, nil
+}
+
+// pathConfigGuardExistenceCheck
+func (b *backend) pathConfigGuardExistenceCheck(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (bool, error) {
+	entry, err := req.Storage.Get(ctx, configGuardStoragePath)
+	if err != nil {
+		return false, err
+	}
+	return entry != nil, nil
+}
+
+// readGuardConfig returns the stored guard config, or the defaults if none is stored.
+func readGuardConfig(ctx context.Context, s logical.Storage) (*guardConfig, error) {
+	config := &guardConfig{}
+	entry, err := s.Get(ctx, configGuardStoragePath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return config, nil
+	}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+const (
+	pathConfigGuardHelpSyn = `
+    Configure the checks made before a login calls TencentCloud.
+    `
+	pathConfigGuardHelpDesc = `
+    Logins, and requests to login/roles, are checked against these settings
+    before any STS or CAM call is made, so that anonymous callers cannot use
+    the mount to flood TencentCloud. allowed_source_cidrs restricts the
+    addresses logins are accepted from for the whole mount; source_rate_limit
+    and source_rate_burst limit how fast each address may log in. Rates are
+    tracked in memory by each Vault node, for up to 4096 addresses, dropping
+    the least recently used. Logins whose address is not known share one
+    rate.
+    `
+)
@@ -0,0 +1,208 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	configLockoutStoragePath = "config/lockout"
+	lockoutMaxFailures       = "max_failures"
+	lockoutFailureWindow     = "failure_window"
+	lockoutDuration          = "lockout_duration"
+
+	defaultLockoutFailureWindow = 15 * time.Minute
+	defaultLockoutDuration      = 15 * time.Minute
+)
+
+// lockoutConfig holds the settings of the failed-login lockout.
+type lockoutConfig struct {
+	// MaxFailures is the number of failed logins within FailureWindow after
+	// which a principal is locked out. 0 disables the lockout.
+	MaxFailures     int           `json:"max_failures"`
+	FailureWindow   time.Duration `json:"failure_window"`
+	LockoutDuration time.Duration `json:"lockout_duration"`
+}
+
+func (c *lockoutConfig) enabled() bool {
+	return c.MaxFailures > 0
+}
+
+func pathConfigLockout(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: configLockoutStoragePath,
+		Fields: map[string]*framework.FieldSchema{
+			lockoutMaxFailures: {
+				Type: framework.TypeInt,
+				Description: "Number of failed logins within failure_window after which a principal is locked out. " +
+					"0 disables the lockout.",
+			},
+			lockoutFailureWindow: {
+				Type:        framework.TypeDurationSecond,
+				Description: "Window in which failed logins are counted.",
+				Default:     int(defaultLockoutFailureWindow.Seconds()),
+			},
+			lockoutDuration: {
+				Type:        framework.TypeDurationSecond,
+				Description: "How long a principal is locked out for.",
+				Default:     int(defaultLockoutDuration.Seconds()),
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.CreateOperation: &framework.PathOperation{
+				Callback: b.pathConfigLockoutWrite,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathConfigLockoutWrite,
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathConfigLockoutRead,
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathConfigLockoutDelete,
+			},
+		},
+		ExistenceCheck:  b.pathConfigLockoutExistenceCheck,
+		HelpSynopsis:    pathConfigLockoutHelpSyn,
+		HelpDescription: pathConfigLockoutHelpDesc,
+	}
+}
+
+// pathConfigLockoutWrite
+func (b *backend) pathConfigLockoutWrite(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := readLockoutConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if raw, ok := data.GetOk(lockoutMaxFailures); ok {
+		config.MaxFailures = raw.(int)
+	}
+	if raw, ok := data.GetOk(lockoutFailureWindow); ok {
+		config.FailureWindow = time.Duration(raw.(int)) * time.Second
+	}
+	if raw, ok := data.GetOk(lockoutDuration); ok {
+		config.LockoutDuration = time.Duration(raw.(int)) * time.Second
+	}
+	if config.MaxFailures < 0 {
+		return logical.ErrorResponse(lockoutMaxFailures + " must not be negative"), nil
+	}
+	if config.FailureWindow <= 0 || config.LockoutDuration <= 0 {
+		return logical.ErrorResponse(lockoutFailureWindow + " and " + lockoutDuration + " must be positive"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(configLockoutStoragePath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+	b.resetLockoutConfig()
+	return nil, nil
+}
+
+// pathConfigLockoutRead
+func (b *backend) pathConfigLockoutRead(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := readLockoutConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			lockoutMaxFailures:   config.MaxFailures,
+			lockoutFailureWindow: int64(config.FailureWindow.Seconds()),
+			lockoutDuration:      int64(config.LockoutDuration.Seconds()),
+		},
+	}, nil
+}
+
+// pathConfigLockoutDelete
+func (b *backend) pathConfigLockoutDelete(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete(ctx, configLockoutStoragePath); err != nil {
+		return nil, err
+	}
+	b.resetLockoutConfig()
+	return nil, nil
+}
+
+// pathConfigLockoutExistenceCheck
+func (b *backend) pathConfigLockoutExistenceCheck(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (bool, error) {
+	entry, err := req.Storage.Get(ctx, configLockoutStoragePath)
+	if err != nil {
+		return false, err
+	}
+	return entry != nil, nil
+}
+
+// readLockoutConfig returns the stored lockout config, or the defaults if none is stored.
+func readLockoutConfig(ctx context.Context, s logical.Storage) (*lockoutConfig, error) {
+	config := &lockoutConfig{
+		FailureWindow:   defaultLockoutFailureWindow,
+		LockoutDuration: defaultLockoutDuration,
+	}
+	entry, err := s.Get(ctx, configLockoutStoragePath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return config, nil
+	}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// getLockoutConfig returns the lockout config, reading it from storage on
+// first use so that logins do not hit storage for it.
+func (b *backend) getLockoutConfig(ctx context.Context, s logical.Storage) (*lockoutConfig, error) {
+	b.lockoutConfigLock.RLock()
+	config := b.lockoutConfig
+	b.lockoutConfigLock.RUnlock()
+	if config != nil {
+		return config, nil
+	}
+
+	b.lockoutConfigLock.Lock()
+	defer b.lockoutConfigLock.Unlock()
+	if b.lockoutConfig != nil {
+		return b.lockoutConfig, nil
+	}
+	config, err := readLockoutConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	b.lockoutConfig = config
+	return config, nil
+}
+
+// resetLockoutConfig drops the lockout config so the next login reads it again.
+func (b *backend) resetLockoutConfig() {
+	b.lockoutConfigLock.Lock()
+	defer b.lockoutConfigLock.Unlock()
+	b.lockoutConfig = nil
+}
+
+const (
+	pathConfigLockoutHelpSyn = `
+    Configure the lockout of principals after failed logins.
+    `
+	pathConfigLockoutHelpDesc = `
+    Failed logins are counted per principal, the arn of the caller's CAM role,
+    and per role. All sessions of a CAM role share a principal, since the
+    session name is chosen by the caller. A principal that fails max_failures
+    times within failure_window is locked out of every role for
+    lockout_duration. Logins that fail before STS has verified the caller,
+    because STS or CAM failed, or because the role is disabled or outside its
+    login windows are not counted. A successful login clears the principal's
+    failures.
+    `
+)
@@ -0,0 +1,220 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault-plugin-auth-tencentcloud/tracing"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	configLoggingStoragePath = "config/logging"
+	decisionLogLevel         = "decision_log_level"
+	traceExporter            = "trace_exporter"
+
+	defaultDecisionLogLevel = "info"
+
+	// Values of trace_exporter.
+	traceExporterNone = "none"
+	traceExporterLog  = "log"
+)
+
+// loggingConfig holds the settings of the login decision log.
+type loggingConfig struct {
+	DecisionLogLevel string `json:"decision_log_level"`
+	// TraceExporter selects where the spans of logins are exported to.
+	TraceExporter string `json:"trace_exporter"`
+}
+
+// level returns the minimum level of decision log records.
+func (c *loggingConfig) level() hclog.Level {
+	return hclog.LevelFromString(c.DecisionLogLevel)
+}
+
+func pathConfigLogging(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: configLoggingStoragePath,
+		Fields: map[string]*framework.FieldSchema{
+			decisionLogLevel: {
+				Type: framework.TypeString,
+				Description: `Minimum level of login decision log records: "trace", "debug", "info", "warn", "error" or "off". ` +
+					`Allowed logins are logged at info, denied ones at warn and internal failures at error.`,
+				Default: defaultDecisionLogLevel,
+			},
+			traceExporter: {
+				Type: framework.TypeString,
+				Description: `Where the tracing spans of logins are exported to: "none", or "log" to write each span ` +
+					`to the plugin's logger at info.`,
+				Default: traceExporterNone,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.CreateOperation: &framework.PathOperation{
+				Callback: b.pathConfigLoggingWrite,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathConfigLoggingWrite,
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathConfigLoggingRead,
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathConfigLoggingDelete,
+			},
+		},
+		ExistenceCheck:  b.pathConfigLoggingExistenceCheck,
+		HelpSynopsis:    pathConfigLoggingHelpSyn,
+		HelpDescription: pathConfigLoggingHelpDesc,
+	}
+}
+
+// pathConfigLoggingWrite
+func (b *backend) pathConfigLoggingWrite(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := readLoggingConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if raw, ok := data.GetOk(decisionLogLevel); ok {
+		config.DecisionLogLevel = strings.ToLower(strings.TrimSpace(raw.(string)))
+	}
+	if config.level() == hclog.NoLevel {
+		return logical.ErrorResponse(fmt.Sprintf("invalid %s %q", decisionLogLevel, config.DecisionLogLevel)), nil
+	}
+	if raw, ok := data.GetOk(traceExporter); ok {
+		config.TraceExporter = strings.ToLower(strings.TrimSpace(raw.(string)))
+	}
+	switch config.TraceExporter {
+	case traceExporterNone, traceExporterLog:
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("invalid %s %q, expected %q or %q",
+			traceExporter, config.TraceExporter, traceExporterNone, traceExporterLog)), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(configLoggingStoragePath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+	b.resetLoggingConfig()
+	return nil, nil
+}
+
+// pathConfigLoggingRead
+func (b *backend) pathConfigLoggingRead(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := readLoggingConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			decisionLogLevel: config.DecisionLogLevel,
+			traceExporter:    config.TraceExporter,
+		},
+	}, nil
+}
+
+// pathConfigLoggingDelete
+func (b *backend) pathConfigLoggingDelete(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete(ctx, configLoggingStoragePath); err != nil {
+		return nil, err
+	}
+	b.resetLoggingConfig()
+	return nil, nil
+}
+
+// pathConfigLoggingExistenceCheck
+func (b *backend) pathConfigLoggingExistenceCheck(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (bool, error) {
+	entry, err := req.Storage.Get(ctx, configLoggingStoragePath)
+	if err != nil {
+		return false, err
+	}
+	return entry != nil, nil
+}
+
+// readLoggingConfig returns the stored logging config, or the defaults if none is stored.
+func readLoggingConfig(ctx context.Context, s logical.Storage) (*loggingConfig, error) {
+	config := &loggingConfig{
+		DecisionLogLevel: defaultDecisionLogLevel,
+		TraceExporter:    traceExporterNone,
+	}
+	entry, err := s.Get(ctx, configLoggingStoragePath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return config, nil
+	}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// getLoggingConfig returns the logging config, reading it from storage on
+// first use so that logins do not hit storage for it.
+func (b *backend) getLoggingConfig(ctx context.Context, s logical.Storage) (*loggingConfig, error) {
+	b.loggingLock.RLock()
+	config := b.logging
+	b.loggingLock.RUnlock()
+	if config != nil {
+		return config, nil
+	}
+
+	b.loggingLock.Lock()
+	defer b.loggingLock.Unlock()
+	if b.logging != nil {
+		return b.logging, nil
+	}
+	config, err := readLoggingConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	b.logging = config
+	return config, nil
+}
+
+// traceContext returns ctx with the span exporter selected by trace_exporter.
+// Without one, spans go to the exporter registered with tracing.SetExporter.
+func (b *backend) traceContext(ctx context.Context, s logical.Storage) context.Context {
+	config, err := b.getLoggingConfig(ctx, s)
+	if err != nil {
+		b.Logger().Error("unable to read the logging config", "error", err)
+		return ctx
+	}
+	if config.TraceExporter != traceExporterLog {
+		return ctx
+	}
+	return tracing.ContextWithExporter(ctx, tracing.NewLogExporter(b.Logger().Named("trace")))
+}
+
+// resetLoggingConfig drops the logging config so the next login reads it again.
+func (b *backend) resetLoggingConfig() {
+	b.loggingLock.Lock()
+	defer b.loggingLock.Unlock()
+	b.logging = nil
+}
+
+const (
+	pathConfigLoggingHelpSyn = `
+    Configure the login decision log.
+    `
+	pathConfigLoggingHelpDesc = `
+    Every login decision is logged through the plugin's logger with the role,
+    caller arn, account, source address, the constraint that failed and how
+    long the login took. Credentials are never logged. decision_log_level
+    takes effect immediately; records must also pass Vault's own log level.
+    trace_exporter set to "log" also writes the tracing spans of logins, dry
+    runs and login/roles requests to the logger, one record per stage.
+    `
+)
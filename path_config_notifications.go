@@ -0,0 +1,346 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	configNotificationsStoragePrefix = "config/notifications/"
+	webhookURL                       = "url"
+	webhookSecret                    = "secret"
+	webhookEvents                    = "events"
+	webhookRoles                     = "roles"
+)
+
+// Event types delivered to webhooks.
+const (
+	eventLoginSuccess  = "login_success"
+	eventLoginDenied   = "login_denied"
+	eventRoleChanged   = "role_changed"
+	eventConfigChanged = "config_changed"
+)
+
+var eventTypes = []string{eventLoginSuccess, eventLoginDenied, eventRoleChanged, eventConfigChanged}
+
+// webhookConfig holds where and which events are delivered.
+type webhookConfig struct {
+	Name   string   `json:"name"`
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+	Roles  []string `json:"roles"`
+}
+
+// wants reports whether an event of type eventType about role is delivered to
+// the webhook. Events that are not about a role are not filtered by roles.
+func (w *webhookConfig) wants(eventType, role string) bool {
+	if !strutil.StrListContains(w.Events, eventType) {
+		return false
+	}
+	if len(w.Roles) == 0 || role == "" {
+		return true
+	}
+	return strutil.StrListContains(w.Roles, role)
+}
+
+func pathConfigNotifications(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: configNotificationsStoragePrefix + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeLowerCaseString,
+				Description: "Name of the webhook.",
+			},
+			webhookURL: {
+				Type:        framework.TypeString,
+				Description: "http or https URL events are POSTed to.",
+			},
+			webhookSecret: {
+				Type:        framework.TypeString,
+				Description: "Secret the HMAC-SHA256 signature of each event is computed with.",
+			},
+			webhookEvents: {
+				Type: framework.TypeCommaStringSlice,
+				Description: `Events to deliver: "login_success", "login_denied", "role_changed" and "config_changed". ` +
+					`Defaults to all of them.`,
+			},
+			webhookRoles: {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "If set, only deliver login and role events about these roles.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.CreateOperation: &framework.PathOperation{
+				Callback: b.pathConfigNotificationsWrite,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathConfigNotificationsWrite,
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathConfigNotificationsRead,
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathConfigNotificationsDelete,
+			},
+		},
+		ExistenceCheck:  b.pathConfigNotificationsExistenceCheck,
+		HelpSynopsis:    pathConfigNotificationsHelpSyn,
+		HelpDescription: pathConfigNotificationsHelpDesc,
+	}
+}
+
+func pathListConfigNotifications(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: configNotificationsStoragePrefix + "?$",
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ListOperation: &framework.PathOperation{
+				Callback: b.pathConfigNotificationsList,
+			},
+		},
+		HelpSynopsis:    pathListConfigNotificationsHelpSyn,
+		HelpDescription: pathListConfigNotificationsHelpDesc,
+	}
+}
+
+func pathNotificationsStatus(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "notifications/status$",
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathNotificationsStatusRead,
+			},
+		},
+		HelpSynopsis:    pathNotificationsStatusHelpSyn,
+		HelpDescription: pathNotificationsStatusHelpDesc,
+	}
+}
+
+// pathConfigNotificationsWrite
+func (b *backend) pathConfigNotificationsWrite(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	config, err := readWebhookConfig(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		if req.Operation == logical.UpdateOperation {
+			return nil, fmt.Errorf("no webhook found to update for %s", name)
+		}
+		config = &webhookConfig{
+			Name:   name,
+			Events: eventTypes,
+		}
+	}
+
+	if raw, ok := data.GetOk(webhookURL); ok {
+		config.URL = strings.TrimSpace(raw.(string))
+	}
+	if raw, ok := data.GetOk(webhookSecret); ok {
+		config.Secret = raw.(string)
+	}
+	if raw, ok := data.GetOk(webhookEvents); ok {
+		config.Events = strutil.RemoveDuplicatesStable(raw.([]string), true)
+	}
+	if raw, ok := data.GetOk(webhookRoles); ok {
+		config.Roles = strutil.RemoveDuplicatesStable(raw.([]string), true)
+	}
+
+	parsed, err := url.Parse(config.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return logical.ErrorResponse("url must be an http or https URL"), nil
+	}
+	if config.Secret == "" {
+		return logical.ErrorResponse("secret is required"), nil
+	}
+	if len(config.Events) == 0 {
+		return logical.ErrorResponse("at least one event is required"), nil
+	}
+	for _, event := range config.Events {
+		if !strutil.StrListContains(eventTypes, event) {
+			return logical.ErrorResponse(fmt.Sprintf("unknown event %q, expected one of %s",
+				event, strings.Join(eventTypes, ", "))), nil
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON(configNotificationsStoragePrefix+name, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+	b.resetWebhooks()
+	return nil, nil
+}
+
+// pathConfigNotificationsRead
+func (b *backend) pathConfigNotificationsRead(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := readWebhookConfig(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			webhookURL:    config.URL,
+			webhookEvents: config.Events,
+			webhookRoles:  config.Roles,
+		},
+	}, nil
+}
+
+// pathConfigNotificationsDelete
+func (b *backend) pathConfigNotificationsDelete(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete(ctx, configNotificationsStoragePrefix+data.Get("name").(string)); err != nil {
+		return nil, err
+	}
+	b.resetWebhooks()
+	return nil, nil
+}
+
+// pathConfigNotificationsExistenceCheck
+func (b *backend) pathConfigNotificationsExistenceCheck(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (bool, error) {
+	config, err := readWebhookConfig(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return config != nil, nil
+}
+
+// pathConfigNotificationsList
+func (b *backend) pathConfigNotificationsList(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	names, err := req.Storage.List(ctx, configNotificationsStoragePrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(names), nil
+}
+
+// pathNotificationsStatusRead
+func (b *backend) pathNotificationsStatusRead(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	status := b.notifier.status()
+	deadLetters := make(map[string]interface{}, len(status.deadLettersByWebhook))
+	for name, count := range status.deadLettersByWebhook {
+		deadLetters[name] = count
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"queue_length":            status.queueLength,
+			"queue_capacity":          status.queueCapacity,
+			"delivered":               status.delivered,
+			"retries":                 status.retries,
+			"dropped":                 status.dropped,
+			"dead_letters":            status.deadLetters,
+			"dead_letters_by_webhook": deadLetters,
+		},
+	}, nil
+}
+
+// readWebhookConfig returns the named webhook, or nil if it does not exist.
+func readWebhookConfig(ctx context.Context, s logical.Storage, name string) (*webhookConfig, error) {
+	entry, err := s.Get(ctx, configNotificationsStoragePrefix+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	config := &webhookConfig{}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, err
+	}
+	config.Name = name
+	return config, nil
+}
+
+// readWebhookConfigs returns all webhooks, sorted by name.
+func readWebhookConfigs(ctx context.Context, s logical.Storage) ([]*webhookConfig, error) {
+	names, err := s.List(ctx, configNotificationsStoragePrefix)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	configs := make([]*webhookConfig, 0, len(names))
+	for _, name := range names {
+		config, err := readWebhookConfig(ctx, s, name)
+		if err != nil {
+			return nil, err
+		}
+		if config != nil {
+			configs = append(configs, config)
+		}
+	}
+	return configs, nil
+}
+
+// getWebhooks returns the configured webhooks, reading them from storage on
+// first use so that logins do not list storage.
+func (b *backend) getWebhooks(ctx context.Context, s logical.Storage) ([]*webhookConfig, error) {
+	b.webhooksLock.RLock()
+	webhooks := b.webhooks
+	b.webhooksLock.RUnlock()
+	if webhooks != nil {
+		return webhooks, nil
+	}
+
+	b.webhooksLock.Lock()
+	defer b.webhooksLock.Unlock()
+	if b.webhooks != nil {
+		return b.webhooks, nil
+	}
+	webhooks, err := readWebhookConfigs(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	b.webhooks = webhooks
+	return webhooks, nil
+}
+
+// resetWebhooks drops the webhooks so the next event reads them again.
+func (b *backend) resetWebhooks() {
+	b.webhooksLock.Lock()
+	defer b.webhooksLock.Unlock()
+	b.webhooks = nil
+}
+
+const (
+	pathConfigNotificationsHelpSyn = `
+    Configure a webhook that receives login and change events.
+    `
+	pathConfigNotificationsHelpDesc = `
+    Events are POSTed as JSON to url, signed with an HMAC-SHA256 of the body
+    keyed with secret. Delivery is asynchronous: events wait in a bounded
+    queue, failed deliveries are retried, and events that could not be
+    delivered are counted as dead letters on notifications/status.
+    `
+	pathListConfigNotificationsHelpSyn = `
+    List the configured webhooks.
+    `
+	pathListConfigNotificationsHelpDesc = `
+    Lists the names of the webhooks under config/notifications.
+    `
+	pathNotificationsStatusHelpSyn = `
+    Report the state of webhook delivery.
+    `
+	pathNotificationsStatusHelpDesc = `
+    Reports the delivery queue, and how many events were delivered, retried,
+    dropped because the queue was full, or dead-lettered after their retries
+    ran out, since the plugin started on this node.
+    `
+)
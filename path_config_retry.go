@@ -0,0 +1,187 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/vault-plugin-auth-tencentcloud/clients"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	configRetryStoragePath = "config/retry"
+	maxRetries             = "max_retries"
+	minRetryDelay          = "min_retry_delay"
+	maxRetryDelay          = "max_retry_delay"
+)
+
+// retryConfig holds the retry settings for TencentCloud API calls.
+type retryConfig struct {
+	MaxRetries    int           `json:"max_retries"`
+	MinRetryDelay time.Duration `json:"min_retry_delay"`
+	MaxRetryDelay time.Duration `json:"max_retry_delay"`
+}
+
+func pathConfigRetry(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: configRetryStoragePath,
+		Fields: map[string]*framework.FieldSchema{
+			maxRetries: {
+				Type:        framework.TypeInt,
+				Description: "Number of retries after a throttled, server-side or transient network failure. 0 disables retries.",
+				Default:     clients.DefaultMaxRetries,
+			},
+			minRetryDelay: {
+				Type:        framework.TypeString,
+				Description: `Upper bound of the jittered delay before the first retry, e.g. "100ms".`,
+				Default:     clients.DefaultMinRetryDelay.String(),
+			},
+			maxRetryDelay: {
+				Type:        framework.TypeString,
+				Description: `Upper bound of the jittered delay between two attempts, e.g. "2s".`,
+				Default:     clients.DefaultMaxRetryDelay.String(),
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.CreateOperation: &framework.PathOperation{
+				Callback: b.pathConfigRetryWrite,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathConfigRetryWrite,
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathConfigRetryRead,
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathConfigRetryDelete,
+			},
+		},
+		ExistenceCheck:  b.pathConfigRetryExistenceCheck,
+		HelpSynopsis:    pathConfigRetryHelpSyn,
+		HelpDescription: pathConfigRetryHelpDesc,
+	}
+}
+
+// pathConfigRetryWrite
+func (b *backend) pathConfigRetryWrite(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := readRetryConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if raw, ok := data.GetOk(maxRetries); ok {
+		config.MaxRetries = raw.(int)
+	}
+	for field, target := range map[string]*time.Duration{
+		minRetryDelay: &config.MinRetryDelay,
+		maxRetryDelay: &config.MaxRetryDelay,
+	} {
+		raw, ok := data.GetOk(field)
+		if !ok {
+			continue
+		}
+		d, err := parseutil.ParseDurationSecond(raw)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid %s: %s", field, err)), nil
+		}
+		*target = d
+	}
+
+	if config.MaxRetries < 0 {
+		return logical.ErrorResponse("max_retries must not be negative"), nil
+	}
+	if config.MinRetryDelay < 0 || config.MaxRetryDelay < config.MinRetryDelay {
+		return logical.ErrorResponse("retry delays must satisfy 0 <= min_retry_delay <= max_retry_delay"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(configRetryStoragePath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+	b.clients.reset()
+	return nil, nil
+}
+
+// pathConfigRetryRead
+func (b *backend) pathConfigRetryRead(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := readRetryConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			maxRetries:    config.MaxRetries,
+			minRetryDelay: config.MinRetryDelay.String(),
+			maxRetryDelay: config.MaxRetryDelay.String(),
+		},
+	}, nil
+}
+
+// pathConfigRetryDelete
+func (b *backend) pathConfigRetryDelete(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete(ctx, configRetryStoragePath); err != nil {
+		return nil, err
+	}
+	b.clients.reset()
+	return nil, nil
+}
+
+// pathConfigRetryExistenceCheck
+func (b *backend) pathConfigRetryExistenceCheck(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (bool, error) {
+	entry, err := req.Storage.Get(ctx, configRetryStoragePath)
+	if err != nil {
+		return false, err
+	}
+	return entry != nil, nil
+}
+
+// readRetryConfig returns the stored retry config, or the defaults if none is stored.
+func readRetryConfig(ctx context.Context, s logical.Storage) (*retryConfig, error) {
+	config := &retryConfig{
+		MaxRetries:    clients.DefaultMaxRetries,
+		MinRetryDelay: clients.DefaultMinRetryDelay,
+		MaxRetryDelay: clients.DefaultMaxRetryDelay,
+	}
+	entry, err := s.Get(ctx, configRetryStoragePath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return config, nil
+	}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// policy converts the config to the policy used by the clients.
+func (c *retryConfig) policy() *clients.RetryPolicy {
+	return &clients.RetryPolicy{
+		MaxRetries: c.MaxRetries,
+		MinDelay:   c.MinRetryDelay,
+		MaxDelay:   c.MaxRetryDelay,
+	}
+}
+
+const (
+	pathConfigRetryHelpSyn = `
+    Configure how failed TencentCloud API calls are retried.
+    `
+	pathConfigRetryHelpDesc = `
+    STS and CAM calls that fail because of throttling (RequestLimitExceeded),
+    server-side errors (InternalError) or transient network failures are
+    retried with jittered exponential backoff. Retries stop once the request
+    that triggered the call is done, whatever max_retries allows.
+    `
+)
@@ -0,0 +1,122 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathLockout(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: lockoutPrefix + framework.GenericNameRegex("id"),
+		Fields: map[string]*framework.FieldSchema{
+			"id": {
+				Type:        framework.TypeString,
+				Description: "Id of the lockout, as listed by LIST lockout/.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathLockoutRead,
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathLockoutDelete,
+			},
+		},
+		HelpSynopsis:    pathLockoutHelpSyn,
+		HelpDescription: pathLockoutHelpDesc,
+	}
+}
+
+func pathListLockouts(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: lockoutPrefix + "?$",
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ListOperation: &framework.PathOperation{
+				Callback: b.pathLockoutList,
+			},
+		},
+		HelpSynopsis:    pathListLockoutsHelpSyn,
+		HelpDescription: pathListLockoutsHelpDesc,
+	}
+}
+
+// pathLockoutRead
+func (b *backend) pathLockoutRead(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	entry, err := readLockoutEntry(ctx, req.Storage, data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	return &logical.Response{
+		Data: entry.toResponseData(time.Now()),
+	}, nil
+}
+
+// pathLockoutDelete
+func (b *backend) pathLockoutDelete(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := b.clearLockout(ctx, req.Storage, data.Get("id").(string)); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// pathLockoutList
+func (b *backend) pathLockoutList(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ids, err := req.Storage.List(ctx, lockoutPrefix)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var locked []string
+	info := make(map[string]interface{})
+	for _, id := range ids {
+		entry, err := readLockoutEntry(ctx, req.Storage, id)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil || !entry.locked(now) {
+			continue
+		}
+		locked = append(locked, id)
+		info[id] = entry.toResponseData(now)
+	}
+	return logical.ListResponseWithInfo(locked, info), nil
+}
+
+func (e *lockoutEntry) toResponseData(now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"principal":        e.Principal,
+		"arn":              e.Arn,
+		"failures":         e.Failures,
+		"failures_by_role": e.FailuresByRole,
+		"window_start":     formatRoleTime(e.WindowStart),
+		"last_failure":     formatRoleTime(e.LastFailure),
+		"locked":           e.locked(now),
+		"locked_until":     formatRoleTime(e.LockedUntil),
+	}
+}
+
+const (
+	pathLockoutHelpSyn = `
+Reads or clears the failed logins of a principal.
+`
+	pathLockoutHelpDesc = `
+Reading returns the principal's failed logins, by role, and when its
+lockout ends. Deleting clears them and ends the lockout.
+`
+	pathListLockoutsHelpSyn = `
+Lists the principals that are locked out.
+`
+	pathListLockoutsHelpDesc = `
+Returns the ids of the principals that are locked out, with their CAM role
+arn, last verified arn, failed logins and when their lockout ends as key info.
+`
+)
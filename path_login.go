@@ -4,39 +4,26 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
+
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault-plugin-auth-tencentcloud/clients"
+	"github.com/hashicorp/vault-plugin-auth-tencentcloud/tracing"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/cidrutil"
 	"github.com/hashicorp/vault/sdk/logical"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/regions"
 )
 
 func pathLogin(b *backend) *framework.Path {
+	fields := loginFields()
+	fields["role"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: roleDescription,
+	}
 	return &framework.Path{
 		Pattern: "login$",
-		Fields: map[string]*framework.FieldSchema{
-			"role": {
-				Type:        framework.TypeString,
-				Description: roleDescription,
-			},
-			"region": {
-				Type:        framework.TypeString,
-				Description: requestRegionDescription,
-			},
-			"secret_id": {
-				Type:        framework.TypeString,
-				Description: requestSecretIdDescription,
-			},
-			"secret_key": {
-				Type:        framework.TypeString,
-				Description: requestSecretKeyDescription,
-			},
-			"token": {
-				Type:        framework.TypeString,
-				Description: requestTokenDescription,
-			},
-		},
+		Fields:  fields,
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.UpdateOperation: &framework.PathOperation{
 				Callback: b.pathLoginUpdate,
@@ -47,105 +34,451 @@ func pathLogin(b *backend) *framework.Path {
 	}
 }
 
+// loginFields returns the fields callers authenticate with.
+func loginFields() map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"region": {
+			Type:        framework.TypeString,
+			Description: requestRegionDescription,
+		},
+		"secret_id": {
+			Type:        framework.TypeString,
+			Description: requestSecretIdDescription,
+		},
+		"secret_key": {
+			Type:        framework.TypeString,
+			Description: requestSecretKeyDescription,
+		},
+		"token": {
+			Type:        framework.TypeString,
+			Description: requestTokenDescription,
+		},
+	}
+}
+
 // checkData
 func checkData(data *framework.FieldData) error {
 	secretId := data.Get("secret_id").(string)
 	if secretId == "" {
-		return errors.New("missing secret id")
+		return newLoginError(errCodeInvalidRequest, "missing secret id", nil)
 	}
 	secretKey := data.Get("secret_key").(string)
 	if secretKey == "" {
-		return errors.New("missing secret key")
+		return newLoginError(errCodeInvalidRequest, "missing secret key", nil)
 	}
 	token := data.Get("token").(string)
 	if token == "" {
-		return errors.New("missing token")
+		return newLoginError(errCodeInvalidRequest, "missing token", nil)
 	}
-	return nil
+	return checkFieldRules(data)
 }
 
 // pathLoginUpdate
 func (b *backend) pathLoginUpdate(ctx context.Context,
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	if err := checkData(data); err != nil {
+	start := time.Now()
+	ctx = b.traceContext(ctx, req.Storage)
+	ctx = tracing.ContextWithTraceParent(ctx, traceParent(req))
+	ctx, span := tracing.Start(ctx, "login")
+	defer span.End()
+
+	attempt := &loginAttempt{}
+	resp, err := b.login(ctx, req, data, attempt)
+	if trackErr := b.trackLogin(ctx, req.Storage, attempt, err); trackErr != nil {
+		// A performance standby cannot record the login, so Vault forwards
+		// it to the active node, which reports and records it instead.
+		if isReadOnly(trackErr) {
+			span.SetError(trackErr)
+			return nil, logical.ErrReadOnly
+		}
+		b.Logger().Error("unable to track the login for the lockout", "principal", attempt.principal,
+			"error", trackErr)
+	}
+	emitLoginMetrics(attempt, start, err)
+	b.logDecision(ctx, req, attempt, start, err)
+	b.notifyLogin(ctx, req, attempt, err)
+	span.SetAttribute("role", attempt.roleName)
+	span.SetAttribute("identity_type", attempt.identityType)
+	if err != nil {
+		span.SetAttribute("code", loginErrorCode(err))
+		span.SetError(err)
+		return b.loginErrorResponse(req, err)
+	}
+	span.SetError(nil)
+	return resp, nil
+}
+
+// loginAttempt collects what is learned about the caller during a login.
+type loginAttempt struct {
+	// identityType is the type of identity STS reported.
+	identityType string
+	// roleName is the Vault role, once it has been read.
+	roleName string
+	// requestedRole is the role named in the request, if any.
+	requestedRole string
+	// arn and account identify the caller, once STS has verified them.
+	arn     string
+	account string
+	// principal is the caller's lockout and login rate key, see caller.
+	principal string
+	// stsRegion is the region whose STS endpoint answered.
+	stsRegion string
+	// lastStage is the last stage the login started.
+	lastStage string
+	// onStage, if set, is called with the outcome of each stage.
+	onStage func(name string, err error)
+}
+
+// caller is an identity verified by STS.
+type caller struct {
+	identity *clients.CallerIdentityRsp
+	// arn is the caller's assumed-role arn, with the CAM role name resolved.
+	arn *arn
+	// region is the region the login requested, or the mount's default.
+	region string
+	// stsRegion is the region whose STS endpoint verified the caller, which
+	// differs from region after a fallback.
+	stsRegion string
+	// camClient, if set, returns a CAM client that can read the caller's
+	// CAM role, and whether it uses the caller's own credentials.
+	camClient func(ctx context.Context) (clients.CAMAPI, bool, error)
+	// principal keys the caller's lockout and login rate. It is the arn of
+	// the caller's CAM role rather than its STS UserId: the UserId includes
+	// the session name, which whoever holds the role's credentials picks
+	// freely, so all sessions of a CAM role share one lockout and rate. Dry
+	// runs without credentials have no principal.
+	principal string
+}
+
+// login authenticates the caller. Failures the caller can act on are
+// returned as a *loginError. Each stage is traced as a child span of ctx.
+func (b *backend) login(ctx context.Context, req *logical.Request,
+	data *framework.FieldData, attempt *loginAttempt) (*logical.Response, error) {
+	if err := attempt.stage(ctx, "guard", func(ctx context.Context, span *tracing.Span) error {
+		return b.checkGuard(ctx, req)
+	}); err != nil {
+		return nil, err
+	}
+	c, err := b.verifyCaller(ctx, req, data, attempt)
+	if err != nil {
+		return nil, err
+	}
+	if err := attempt.stage(ctx, "check_lockout", func(ctx context.Context, span *tracing.Span) error {
+		return b.checkLockout(ctx, req.Storage, c.principal)
+	}); err != nil {
+		return nil, err
+	}
+
+	roleName := ""
+	roleNameIfc, ok := data.GetOk("role")
+	if ok {
+		roleName = roleNameIfc.(string)
+	}
+	attempt.requestedRole = roleName
+	var role *roleEntry
+	if err := attempt.stage(ctx, "read_role", func(ctx context.Context, span *tracing.Span) (err error) {
+		if roleName == "" {
+			if roleName, err = resolveRoleName(ctx, req.Storage, c.arn); err != nil {
+				return err
+			}
+		}
+		role, err = b.readRole(ctx, req.Storage, roleName)
+		if err != nil {
+			return err
+		}
+		if role == nil {
+			return newLoginError(errCodeRoleNotFound, fmt.Sprintf("entry for role %s not found", roleName), nil)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	attempt.roleName = roleName
+
+	if err := b.checkRole(ctx, req, attempt, c, role, roleName); err != nil {
+		return nil, err
+	}
+	// Only logins that passed every other check count towards the rate.
+	if err := attempt.stage(ctx, "check_rate", func(ctx context.Context, span *tracing.Span) error {
+		return b.checkLoginRate(role, roleName, c.principal, false)
+	}); err != nil {
+		return nil, err
+	}
+
+	var auth *logical.Auth
+	if err := attempt.stage(ctx, "build_token", func(ctx context.Context, span *tracing.Span) error {
+		auth = makeAuth(c.identity, c.arn, roleName)
+		auth.Metadata["sts_region"] = c.stsRegion
+		role.PopulateTokenAuth(auth)
+		capTokenLifetime(auth, role, time.Now())
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return &logical.Response{
+		Auth: auth,
+	}, nil
+}
+
+// verifyCaller verifies the caller's credentials with STS and resolves the
+// name of the CAM role it assumed.
+func (b *backend) verifyCaller(ctx context.Context, req *logical.Request,
+	data *framework.FieldData, attempt *loginAttempt) (*caller, error) {
+	if err := attempt.stage(ctx, "validate_input", func(ctx context.Context, span *tracing.Span) error {
+		return checkData(data)
+	}); err != nil {
 		return nil, err
 	}
 	sId := data.Get("secret_id").(string)
 	sKey := data.Get("secret_key").(string)
 	token := data.Get("token").(string)
 	region := data.Get("region").(string)
-	if region == "" {
-		region = regions.Ashburn
-	}
 
-	stsClient, err := clients.NewStsClient(sId, sKey, token, region)
+	api, err := b.readAPIConfig(ctx, req.Storage)
 	if err != nil {
 		return nil, err
 	}
-	ciRsp, err := stsClient.GetCallerIdentity()
-	if err != nil {
-		return nil, err
+	c := &caller{region: region}
+	if c.region == "" {
+		c.region = api.endpoints.DefaultRegion
 	}
-	if ciRsp.Type != "CAMRole" {
-		return nil, fmt.Errorf(" %s arn types are not supported at this time", ciRsp.Type)
+	c.camClient = func(ctx context.Context) (clients.CAMAPI, bool, error) {
+		return b.camClientForAccount(ctx, req.Storage, api, c.arn.Uin, sId, sKey, token)
 	}
-	parsedARN, err := parseARN(ciRsp.Arn)
-	if err != nil {
-		return nil, errwrap.Wrapf(fmt.Sprintf(
-			"unable to parse entity's arn %s due to {{err}}", ciRsp.Arn), err)
+	if err := attempt.stage(ctx, "sts", func(ctx context.Context, span *tracing.Span) (err error) {
+		c.identity, c.stsRegion, err = b.getCallerIdentity(ctx, api, region, sId, sKey, token)
+		if err != nil {
+			return upstreamLoginError("STS", true, err)
+		}
+		span.SetAttribute("region", c.stsRegion)
+		return nil
+	}); err != nil {
+		return nil, err
 	}
-	if parsedARN.Type != arnAssumedRoleType {
-		return nil, fmt.Errorf(
-			"only %s arn types are supported at this time, but %s was provided",
-			arnAssumedRoleType, parsedARN.Type)
+	attempt.identityType = c.identity.Type
+	attempt.arn = c.identity.Arn
+	attempt.account = c.identity.AccountId
+	attempt.stsRegion = c.stsRegion
+
+	if err := attempt.stage(ctx, "parse_arn", func(ctx context.Context, span *tracing.Span) (err error) {
+		if c.identity.Type != "CAMRole" {
+			return newLoginError(errCodeUnsupportedIdentityType,
+				fmt.Sprintf("%s identities are not supported at this time", c.identity.Type), nil)
+		}
+		c.arn, err = parseARN(c.identity.Arn)
+		if err != nil {
+			return newLoginError(errCodeUnsupportedIdentityType, "unable to parse the caller's arn", errwrap.Wrapf(
+				fmt.Sprintf("unable to parse entity's arn %s due to {{err}}", c.identity.Arn), err))
+		}
+		if c.arn.Type != arnAssumedRoleType {
+			return newLoginError(errCodeUnsupportedIdentityType, fmt.Sprintf(
+				"only %s arn types are supported at this time, but %s was provided",
+				arnAssumedRoleType, c.arn.Type), nil)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
 	}
 
 	// get roleName from tencentCloud
-	camClient, err := clients.NewCAMClient(sId, sKey, token)
-	if err != nil {
+	if err := attempt.stage(ctx, "cam_lookup", func(ctx context.Context, span *tracing.Span) error {
+		roleNames, err := b.getRoleNameCache(ctx, req.Storage)
+		if err != nil {
+			return err
+		}
+		span.SetAttribute("cache", "hit")
+		key := c.arn.Uin + "/" + c.arn.RoleId
+		ownLookup := false
+		lookup := func(ctx context.Context) (string, error) {
+			ownLookup = true
+			span.SetAttribute("cache", "miss")
+			camClient, callerCreds, err := c.camClient(ctx)
+			if err != nil {
+				return "", newLoginError(errCodeUpstreamError, "unable to look up the caller's CAM role", err)
+			}
+			roleName, err := camClient.GetRoleName(ctx, c.arn.RoleId)
+			if err != nil {
+				return "", upstreamLoginError("CAM", callerCreds, err)
+			}
+			return roleName, nil
+		}
+		c.arn.RoleName, err = roleNames.get(ctx, key, lookup)
+		if err != nil && ctx.Err() == nil && !ownLookup && clients.IsAuthFailure(err) {
+			// Without an account config, a shared lookup was made with
+			// another caller's credentials, which may not be allowed what
+			// this caller's are.
+			account, accountErr := b.accountConfigName(ctx, req.Storage, c.arn.Uin)
+			if accountErr != nil {
+				return accountErr
+			}
+			if account == "" {
+				c.arn.RoleName, err = lookup(ctx)
+				roleNames.store(key, c.arn.RoleName, err)
+			}
+		}
+		if err != nil && ctx.Err() != nil {
+			// The login gave up waiting for a lookup shared with others.
+			return upstreamLoginError("CAM", false, err)
+		}
+		return err
+	}); err != nil {
 		return nil, err
 	}
-	parsedRoleName, err := camClient.GetRoleName(parsedARN.RoleId)
-	if err != nil {
-		return nil, err
+	c.principal = c.arn.roleARN()
+	attempt.principal = c.principal
+	return c, nil
+}
+
+// checkInput is what the constraints of a role are checked against.
+type checkInput struct {
+	caller   *caller
+	role     *roleEntry
+	roleName string
+	// sourceIP is the caller's address, or empty if it is not known.
+	sourceIP string
+	// now is the time of the login.
+	now time.Time
+	// tags returns the tags of the caller's CAM role.
+	tags func() (map[string]string, error)
+}
+
+func newCheckInput(ctx context.Context, c *caller, role *roleEntry, roleName, sourceIP string) *checkInput {
+	return &checkInput{
+		caller:   c,
+		role:     role,
+		roleName: roleName,
+		sourceIP: sourceIP,
+		now:      time.Now(),
+		tags:     roleTagsLoader(ctx, c),
 	}
-	parsedARN.RoleName = parsedRoleName
-	roleName := ""
-	roleNameIfc, ok := data.GetOk("role")
-	if ok {
-		roleName = roleNameIfc.(string)
+}
+
+// roleCheck is one constraint of a role.
+type roleCheck struct {
+	name  string
+	check func(in *checkInput) error
+}
+
+// roleChecks are the constraints a caller must pass to log in to a role, in
+// the order they are checked.
+var roleChecks = []roleCheck{
+	{name: "check_cidr", check: checkCIDR},
+	{name: "check_arn", check: checkARN},
+	// The reason a role is disabled is only shown to callers bound to it.
+	{name: "check_disabled", check: checkDisabled},
+	{name: "check_time", check: checkLoginTime},
+	{name: "check_conditions", check: checkConditions},
+}
+
+// checkRole checks the constraints of role against the caller, stopping at
+// the first one that fails.
+func (b *backend) checkRole(ctx context.Context, req *logical.Request, attempt *loginAttempt,
+	c *caller, role *roleEntry, roleName string) error {
+	in := newCheckInput(ctx, c, role, roleName, sourceIP(req))
+	for _, check := range roleChecks {
+		check := check
+		if err := attempt.stage(ctx, check.name, func(ctx context.Context, span *tracing.Span) error {
+			return check.check(in)
+		}); err != nil {
+			return err
+		}
 	}
-	if roleName == "" {
-		roleName = parsedARN.RoleName
+	return nil
+}
+
+// checkCIDR checks the caller's address against the role's bound CIDRs.
+func checkCIDR(in *checkInput) error {
+	if len(in.role.TokenBoundCIDRs) == 0 {
+		return nil
+	}
+	if in.sourceIP == "" {
+		return newLoginError(errCodeCIDRDenied, "the source address could not be verified", nil)
+	}
+	if !cidrutil.RemoteAddrIsOk(in.sourceIP, in.role.TokenBoundCIDRs) {
+		return newLoginError(errCodeCIDRDenied, "the source address is not allowed by the role",
+			fmt.Errorf("%s is not in the bound CIDRs of role %s", in.sourceIP, in.roleName))
+	}
+	return nil
+}
+
+// checkARN checks that the caller assumed the CAM role bound to the role.
+func checkARN(in *checkInput) error {
+	if !in.caller.arn.IsMemberOf(in.role.ARN) {
+		return newLoginError(errCodeARNMismatch, "the caller's arn does not match the role's arn",
+			fmt.Errorf("%s (%s) is not bound to role %s", in.caller.identity.Arn, in.caller.arn.RoleName, in.roleName))
 	}
-	role, err := readRole(ctx, req.Storage, roleName)
+	return nil
+}
+
+// stage runs one stage of a login in a child span of ctx, and records it as
+// the stage a failed login stopped at.
+func (a *loginAttempt) stage(ctx context.Context, name string, fn func(ctx context.Context, span *tracing.Span) error) error {
+	a.lastStage = name
+	ctx, span := tracing.Start(ctx, "login."+name)
+	defer span.End()
+	err := fn(ctx, span)
+	span.SetError(err)
+	if a.onStage != nil {
+		a.onStage(name, err)
+	}
+	return err
+}
+
+// resolveRoleName picks the Vault role for a login that names none: the
+// first role bound to the caller's CAM role, or else the Vault role named
+// like the CAM role.
+func resolveRoleName(ctx context.Context, s logical.Storage, parsedARN *arn) (string, error) {
+	names, err := rolesForARN(ctx, s, parsedARN.Uin, parsedARN.RoleName)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	if role == nil {
-		return nil, fmt.Errorf("entry for role %s not found", parsedARN.RoleName)
+	if len(names) > 0 {
+		return names[0], nil
 	}
-	if len(role.TokenBoundCIDRs) > 0 {
-		if req.Connection == nil {
-			b.Logger().Warn("token bound CIDRs found but no connection information available for validation")
-			return nil, logical.ErrPermissionDenied
-		}
-		if !cidrutil.RemoteAddrIsOk(req.Connection.RemoteAddr, role.TokenBoundCIDRs) {
-			return nil, logical.ErrPermissionDenied
+	return parsedARN.RoleName, nil
+}
+
+// traceParent returns the W3C traceparent header of the request, if Vault
+// was configured to pass it through.
+func traceParent(req *logical.Request) string {
+	for name, values := range req.Headers {
+		if strings.EqualFold(name, "traceparent") && len(values) > 0 {
+			return values[0]
 		}
 	}
-	if !parsedARN.IsMemberOf(role.ARN) {
-		return nil, errors.New("the caller's arn does not match the role's arn")
+	return ""
+}
+
+// getCallerIdentity verifies the caller's credentials with STS, trying the
+// fallback regions in order while the previous region fails with a network or
+// server error. It returns the region that answered.
+func (b *backend) getCallerIdentity(ctx context.Context, api *apiConfig,
+	region, sId, sKey, token string) (*clients.CallerIdentityRsp, string, error) {
+	creds, err := clients.ChainedCredsToCli(sId, sKey, token)
+	if err != nil {
+		return nil, "", err
 	}
-	auth := makeAuth(ciRsp, roleName)
-	role.PopulateTokenAuth(auth)
-	return &logical.Response{
-		Auth: auth,
-	}, nil
+	var lastErr error
+	for _, region := range api.endpoints.stsRegions(region) {
+		stsClient, err := b.clientFactory.NewSTSClient(creds, api.stsConfig(region))
+		if err != nil {
+			return nil, "", err
+		}
+		ciRsp, err := stsClient.GetCallerIdentity(ctx)
+		if err == nil {
+			return ciRsp, region, nil
+		}
+		if !clients.IsRegionalFailure(err) || ctx.Err() != nil {
+			return nil, "", err
+		}
+		b.Logger().Warn("GetCallerIdentity failed, trying the next region", "region", region, "error", err)
+		lastErr = err
+	}
+	return nil, "", lastErr
 }
 
 // makeAuth
-func makeAuth(callerIdentity *clients.CallerIdentityRsp, roleName string) (auth *logical.Auth) {
+func makeAuth(callerIdentity *clients.CallerIdentityRsp, parsedARN *arn, roleName string) (auth *logical.Auth) {
 	return &logical.Auth{
 		Metadata: map[string]string{
 			"role_id":       roleName,
@@ -157,6 +490,7 @@ func makeAuth(callerIdentity *clients.CallerIdentityRsp, roleName string) (auth
 			"request_id":    callerIdentity.RequestId,
 			"identity_type": callerIdentity.Type,
 			"role_name":     roleName,
+			"cam_role_name": parsedARN.RoleName,
 		},
 		DisplayName: callerIdentity.PrincipalId,
 		Alias: &logical.Alias{
@@ -177,37 +511,59 @@ func (b *backend) pathLoginRenew(ctx context.Context,
 	if err != nil {
 		return nil, err
 	}
+	// The assumed-role arn only carries the role id, so restore the CAM role
+	// name resolved at login.
+	parsedARN.RoleName = req.Auth.Metadata["cam_role_name"]
 
 	roleName, ok := req.Auth.Metadata["role_name"]
 	if !ok {
 		return nil, errors.New("error retrieving role_name during renewal")
 	}
 
-	role, err := readRole(ctx, req.Storage, roleName)
+	role, err := b.readRole(ctx, req.Storage, roleName)
 	if err != nil {
 		return nil, err
 	}
 	if role == nil {
 		return nil, errors.New("role entry not found")
 	}
+	if role.Disabled {
+		return nil, fmt.Errorf("role %s is disabled, not renewing", roleName)
+	}
 
 	if !parsedARN.IsMemberOf(role.ARN) {
 		return nil, errors.New("the caller's arn does not match the role's arn")
 	}
+	if role.renewalVerification() == renewalVerificationCAM {
+		if err := b.verifyCAMRole(ctx, req.Storage, parsedARN); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := checkRenewalCIDRs(req, role); err != nil {
+		return nil, err
+	}
 
 	resp := &logical.Response{Auth: req.Auth}
+	if err := checkPolicyDrift(resp.Auth, role, roleName); err != nil {
+		return nil, err
+	}
 	resp.Auth.TTL = role.TokenTTL
 	resp.Auth.MaxTTL = role.TokenMaxTTL
 	resp.Auth.Period = role.TokenPeriod
+	if err := checkRenewalTime(resp.Auth, role, time.Now()); err != nil {
+		return nil, err
+	}
 	return resp, nil
 }
 
 const (
 	roleDescription = `Name of the role against which the login is being attempted.
-If 'role' is not specified, then the login endpoint looks for a role name in the ARN returned by
-the GetCallerIdentity request. If a matching role is not found, login fails.`
+If 'role' is not specified, then the login endpoint uses the role bound to the caller's CAM role,
+preferring a role with the same name as the CAM role and then the first by name. If no role is
+bound, it looks for a role with the name of the CAM role. If a matching role is not found, login fails.`
 
-	requestRegionDescription    = `Region parameter, used to identify the region whose data you want to operate.`
+	requestRegionDescription    = `Region parameter, used to identify the region whose data you want to operate. Defaults to the mount's default_region.`
 	requestSecretIdDescription  = `Temporary certificate key ID. The maximum length is 1024 bytes.`
 	requestSecretKeyDescription = `Temporary certificate key. The maximum length is 1024 bytes.`
 	requestTokenDescription     = `The length of the token depends on the binding policy and is no longer than 4096 bytes.`
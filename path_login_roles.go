@@ -0,0 +1,111 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault-plugin-auth-tencentcloud/tracing"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathLoginRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "login/roles$",
+		Fields:  loginFields(),
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathLoginRolesUpdate,
+			},
+		},
+		HelpSynopsis:    pathLoginRolesSyn,
+		HelpDescription: pathLoginRolesDesc,
+	}
+}
+
+// pathLoginRolesUpdate
+func (b *backend) pathLoginRolesUpdate(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ctx = b.traceContext(ctx, req.Storage)
+	ctx = tracing.ContextWithTraceParent(ctx, traceParent(req))
+	ctx, span := tracing.Start(ctx, "login_roles")
+	defer span.End()
+
+	attempt := &loginAttempt{}
+	err := attempt.stage(ctx, "guard", func(ctx context.Context, span *tracing.Span) error {
+		return b.checkGuard(ctx, req)
+	})
+	var c *caller
+	if err == nil {
+		c, err = b.verifyCaller(ctx, req, data, attempt)
+	}
+	if err == nil {
+		err = attempt.stage(ctx, "check_lockout", func(ctx context.Context, span *tracing.Span) error {
+			return b.checkLockout(ctx, req.Storage, c.principal)
+		})
+	}
+	if err != nil {
+		span.SetError(err)
+		return b.loginErrorResponse(req, err)
+	}
+	roles, err := b.allowedRoles(ctx, req, attempt, c)
+	span.SetError(err)
+	if err != nil {
+		return nil, err
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"arn":           c.identity.Arn,
+			"cam_role_name": c.arn.RoleName,
+			"roles":         roles,
+		},
+	}, nil
+}
+
+// allowedRoles returns the roles the caller may log in to, in the order a
+// login without a role name prefers them.
+func (b *backend) allowedRoles(ctx context.Context, req *logical.Request,
+	attempt *loginAttempt, c *caller) ([]map[string]interface{}, error) {
+	names, err := rolesForARN(ctx, req.Storage, c.arn.Uin, c.arn.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	if !strutil.StrListContains(names, c.arn.RoleName) {
+		names = append(names, c.arn.RoleName)
+	}
+
+	roles := []map[string]interface{}{}
+	for _, name := range names {
+		role, err := b.readRole(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			continue
+		}
+		var loginErr *loginError
+		if err := b.checkRole(ctx, req, attempt, c, role, name); errors.As(err, &loginErr) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		roles = append(roles, map[string]interface{}{
+			"role":           name,
+			"token_policies": role.TokenPolicies,
+			"token_ttl":      int64(role.TokenTTL.Seconds()),
+			"token_max_ttl":  int64(role.TokenMaxTTL.Seconds()),
+			"token_period":   int64(role.TokenPeriod.Seconds()),
+		})
+	}
+	return roles, nil
+}
+
+const (
+	pathLoginRolesSyn  = `Lists the roles a TencentCloud identity can log in to.`
+	pathLoginRolesDesc = `
+Verifies the caller's credentials and lockout in the same way as login, and
+returns the roles the caller may log in to with their policies and TTLs, in
+the order a login without a role name tries them. No token is issued.
+`
+)
@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/tokenutil"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -24,6 +27,42 @@ func pathRole(b *backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: "ARN of the CAM to bind to this role.",
 			},
+			"renewal_verification": {
+				Type: framework.TypeString,
+				Description: `How the caller's CAM role is checked again when a token is renewed. ` +
+					`"none" only checks the role's arn; "cam" also checks with CAM that the role still exists ` +
+					`and has the RoleId it had at login.`,
+				Default: renewalVerificationNone,
+			},
+			"conditions": {
+				Type: framework.TypeStringSlice,
+				Description: `Expressions over the caller's attributes, such as ` +
+					`'account_id in ["1000262888"] && session_name.startsWith("deploy-")'. ` +
+					`A login succeeds only if all of them are true.`,
+			},
+			"not_before": {
+				Type:        framework.TypeString,
+				Description: `RFC 3339 time before which logins to the role are denied. Empty to clear.`,
+			},
+			"not_after": {
+				Type: framework.TypeString,
+				Description: `RFC 3339 time from which logins to the role are denied. Tokens of the role ` +
+					`expire no later than this time. Empty to clear.`,
+			},
+			"allowed_login_windows": {
+				Type: framework.TypeStringSlice,
+				Description: `Recurring windows in which logins are allowed, such as "Mon-Fri 09:00-17:30" ` +
+					`or "* 22:00-02:00". If empty, logins are allowed at any time.`,
+			},
+			"login_window_time_zone": {
+				Type:        framework.TypeString,
+				Description: `IANA time zone of allowed_login_windows, such as "Asia/Shanghai".`,
+				Default:     "UTC",
+			},
+			"max_logins_per_minute": {
+				Type:        framework.TypeInt,
+				Description: "Maximum number of successful logins of each CAM role to the role per minute, across its sessions. 0 is unlimited.",
+			},
 			"policies": {
 				Type:        framework.TypeCommaStringSlice,
 				Description: tokenutil.DeprecationText("token_policies"),
@@ -97,10 +136,32 @@ func pathListRoles(b *backend) *framework.Path {
 	}
 }
 
+func pathRoleByARN(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "role-by-arn$",
+		Fields: map[string]*framework.FieldSchema{
+			"arn": {
+				Type:        framework.TypeString,
+				Description: "ARN of a CAM role, e.g. qcs::cam::uin/<uin>:roleName/<name>.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathRoleByARNRead,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathRoleByARNRead,
+			},
+		},
+		HelpSynopsis:    pathRoleByARNHelpSyn,
+		HelpDescription: pathRoleByARNHelpDesc,
+	}
+}
+
 // operationRoleExistenceCheck
 func (b *backend) operationRoleExistenceCheck(ctx context.Context,
 	req *logical.Request, data *framework.FieldData) (bool, error) {
-	entry, err := readRole(ctx, req.Storage, data.Get("role").(string))
+	entry, err := b.readRole(ctx, req.Storage, data.Get("role").(string))
 	if err != nil {
 		return false, err
 	}
@@ -111,7 +172,7 @@ func (b *backend) operationRoleExistenceCheck(ctx context.Context,
 func (b *backend) pathRoleWrite(ctx context.Context,
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	roleName := data.Get("role").(string)
-	role, err := readRole(ctx, req.Storage, roleName)
+	role, err := b.readRole(ctx, req.Storage, roleName)
 	if err != nil {
 		return nil, err
 	}
@@ -132,6 +193,51 @@ func (b *backend) pathRoleWrite(ctx context.Context,
 	} else if req.Operation == logical.CreateOperation {
 		return nil, errors.New("the arn is required to create a role")
 	}
+	if raw, ok := data.GetOk("renewal_verification"); ok {
+		switch verification := raw.(string); verification {
+		case renewalVerificationNone, renewalVerificationCAM:
+			role.RenewalVerification = verification
+		default:
+			return logical.ErrorResponse(fmt.Sprintf("invalid renewal_verification %q, expected %q or %q",
+				verification, renewalVerificationNone, renewalVerificationCAM)), nil
+		}
+	}
+	if raw, ok := data.GetOk("conditions"); ok {
+		exprs := raw.([]string)
+		if role.conditions, err = parseConditions(exprs); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		role.Conditions = exprs
+	}
+	for field, t := range map[string]*time.Time{"not_before": &role.NotBefore, "not_after": &role.NotAfter} {
+		if raw, ok := data.GetOk(field); ok {
+			if *t, err = parseRoleTime(field, raw.(string)); err != nil {
+				return logical.ErrorResponse(err.Error()), nil
+			}
+		}
+	}
+	if !role.NotBefore.IsZero() && !role.NotAfter.IsZero() && !role.NotBefore.Before(role.NotAfter) {
+		return logical.ErrorResponse("not_before must be before not_after"), nil
+	}
+	if raw, ok := data.GetOk("allowed_login_windows"); ok {
+		role.LoginWindows = raw.([]string)
+	}
+	if raw, ok := data.GetOk("login_window_time_zone"); ok {
+		zone := raw.(string)
+		if _, err := time.LoadLocation(zone); err != nil || zone == "" || strings.EqualFold(zone, "local") {
+			return logical.ErrorResponse(fmt.Sprintf("invalid login_window_time_zone %q", zone)), nil
+		}
+		role.LoginWindowTimeZone = zone
+	}
+	if role.loginWindows, role.loginLocation, err = parseRoleLoginWindows(role); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if raw, ok := data.GetOk("max_logins_per_minute"); ok {
+		if raw.(int) < 0 {
+			return logical.ErrorResponse("max_logins_per_minute must not be negative"), nil
+		}
+		role.MaxLoginsPerMinute = raw.(int)
+	}
 	if err := role.ParseTokenFields(req, data); err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
@@ -163,7 +269,7 @@ func (b *backend) pathRoleWrite(ctx context.Context,
 	if role.TokenMaxTTL > 0 && role.TokenTTL > role.TokenMaxTTL {
 		return nil, errors.New("ttl exceeds max ttl")
 	}
-	err = saveRole(ctx, role, req.Storage, roleName)
+	err = b.saveRole(ctx, role, req.Storage, roleName)
 	if err != nil {
 		return nil, err
 	}
@@ -181,7 +287,7 @@ func (b *backend) pathRoleWrite(ctx context.Context,
 // pathRoleWrite
 func (b *backend) pathRoleRead(ctx context.Context,
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	role, err := readRole(ctx, req.Storage, data.Get("role").(string))
+	role, err := b.readRole(ctx, req.Storage, data.Get("role").(string))
 	if err != nil {
 		return nil, err
 	}
@@ -196,12 +302,38 @@ func (b *backend) pathRoleRead(ctx context.Context,
 // pathRoleDelete
 func (b *backend) pathRoleDelete(ctx context.Context,
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	if err := req.Storage.Delete(ctx, "role/"+data.Get("role").(string)); err != nil {
+	if err := b.deleteRole(ctx, req.Storage, data.Get("role").(string)); err != nil {
 		return nil, err
 	}
 	return nil, nil
 }
 
+// pathRoleByARNRead
+func (b *backend) pathRoleByARNRead(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	parsed, err := parseARN(data.Get("arn").(string))
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("unable to parse arn: %s", err)), nil
+	}
+	if parsed.Type != arnRoleType {
+		return logical.ErrorResponse(fmt.Sprintf("only %s arns are supported, but %s was provided",
+			arnRoleType, parsed.Type)), nil
+	}
+	roleNames, err := rolesForARN(ctx, req.Storage, parsed.Uin, parsed.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	if roleNames == nil {
+		roleNames = []string{}
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"arn":   parsed.String(),
+			"roles": roleNames,
+		},
+	}, nil
+}
+
 // pathRoleList
 func (b *backend) pathRoleList(ctx context.Context,
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -212,21 +344,61 @@ func (b *backend) pathRoleList(ctx context.Context,
 	return logical.ListResponse(roleNames), nil
 }
 
-// saveRole
-func saveRole(ctx context.Context, role *roleEntry, s logical.Storage, roleName string) error {
+// saveRole stores the role and moves its arn index entry to its current arn.
+func (b *backend) saveRole(ctx context.Context, role *roleEntry, s logical.Storage, roleName string) error {
+	previous, err := readStoredRole(ctx, s, roleName)
+	if err != nil {
+		return err
+	}
 	entry, err := logical.StorageEntryJSON(rolePath+roleName, role)
 	if err != nil {
 		return err
 	}
 	if err = s.Put(ctx, entry); err != nil {
+		b.roles.remove(roleName)
 		return err
 	}
-	return nil
+	b.roles.set(roleName, role)
+	var previousARN *arn
+	if previous != nil {
+		previousARN = previous.ARN
+	}
+	return updateARNIndex(ctx, s, roleName, previousARN, role.ARN)
+}
+
+// deleteRole deletes the role and its arn index entry.
+func (b *backend) deleteRole(ctx context.Context, s logical.Storage, roleName string) error {
+	defer b.roles.remove(roleName)
+	previous, err := readStoredRole(ctx, s, roleName)
+	if err != nil {
+		return err
+	}
+	if err := s.Delete(ctx, rolePath+roleName); err != nil {
+		return err
+	}
+	if previous == nil {
+		return nil
+	}
+	return updateARNIndex(ctx, s, roleName, previous.ARN, nil)
 }
 
-// readRole
-func readRole(ctx context.Context, s logical.Storage, roleName string) (*roleEntry, error) {
-	role, err := s.Get(ctx, "role/"+roleName)
+// readRole returns the role from the cache, reading it from storage on a miss.
+func (b *backend) readRole(ctx context.Context, s logical.Storage, roleName string) (*roleEntry, error) {
+	if role, ok := b.roles.get(roleName); ok {
+		return role, nil
+	}
+	gen := b.roles.generation()
+	role, err := readStoredRole(ctx, s, roleName)
+	if err != nil || role == nil {
+		return nil, err
+	}
+	b.roles.fill(gen, roleName, role)
+	return role, nil
+}
+
+// readStoredRole decodes the role from storage, upgrading deprecated fields.
+func readStoredRole(ctx context.Context, s logical.Storage, roleName string) (*roleEntry, error) {
+	role, err := s.Get(ctx, rolePath+roleName)
 	if err != nil {
 		return nil, err
 	}
@@ -254,6 +426,12 @@ func readRole(ctx context.Context, s logical.Storage, roleName string) (*roleEnt
 		result.TokenBoundCIDRs = result.BoundCIDRs
 	}
 
+	if result.conditions, err = parseConditions(result.Conditions); err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("unable to read role %s due to {{err}}", roleName), err)
+	}
+	if result.loginWindows, result.loginLocation, err = parseRoleLoginWindows(result); err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("unable to read role %s due to {{err}}", roleName), err)
+	}
 	return result, nil
 }
 
@@ -269,6 +447,14 @@ that are associated to the role though this endpoint.
 Also, a 'max_ttl' can be configured in this endpoint that determines the maximum
 duration for which a login can be renewed. Note that the 'max_ttl' has an upper
 limit of the 'max_ttl' value on the backend's mount. The same applies to the 'ttl'.
+`
+	pathRoleByARNHelpSyn = `
+Lists the roles bound to a CAM role arn.
+`
+	pathRoleByARNHelpDesc = `
+Returns the roles whose arn is the given CAM role arn, in the order a login
+that does not name a role tries them: the role named like the CAM role
+first, then the others by name.
 `
 	pathListRolesHelpSyn = `
 Lists all the roles that are registered with Vault.
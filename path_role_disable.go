@@ -0,0 +1,112 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathRoleDisable(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: rolePath + framework.GenericNameRegex("role") + "/disable$",
+		Fields: map[string]*framework.FieldSchema{
+			"role": {
+				Type:        framework.TypeLowerCaseString,
+				Description: "Name of the role to disable.",
+			},
+			"reason": {
+				Type:        framework.TypeString,
+				Description: "Why the role is disabled. It is returned to callers whose login is denied.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathRoleDisableUpdate,
+			},
+		},
+		HelpSynopsis:    pathRoleDisableSyn,
+		HelpDescription: pathRoleDisableDesc,
+	}
+}
+
+func pathRoleEnable(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: rolePath + framework.GenericNameRegex("role") + "/enable$",
+		Fields: map[string]*framework.FieldSchema{
+			"role": {
+				Type:        framework.TypeLowerCaseString,
+				Description: "Name of the role to enable.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathRoleEnableUpdate,
+			},
+		},
+		HelpSynopsis:    pathRoleEnableSyn,
+		HelpDescription: pathRoleEnableDesc,
+	}
+}
+
+// pathRoleDisableUpdate
+func (b *backend) pathRoleDisableUpdate(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.setRoleDisabled(ctx, req, data.Get("role").(string), true, data.Get("reason").(string))
+}
+
+// pathRoleEnableUpdate
+func (b *backend) pathRoleEnableUpdate(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.setRoleDisabled(ctx, req, data.Get("role").(string), false, "")
+}
+
+// setRoleDisabled disables or enables a role, keeping the rest of it.
+func (b *backend) setRoleDisabled(ctx context.Context, req *logical.Request,
+	roleName string, disabled bool, reason string) (*logical.Response, error) {
+	role, err := b.readRole(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("role %s not found", roleName)), nil
+	}
+	role.Disabled = disabled
+	role.DisabledReason = reason
+	role.DisabledAt = time.Time{}
+	if disabled {
+		role.DisabledAt = time.Now().UTC()
+	}
+	if err := b.saveRole(ctx, role, req.Storage, roleName); err != nil {
+		return nil, err
+	}
+	b.Logger().Info("role state changed", "role", roleName, "disabled", disabled, "reason", reason)
+	return nil, nil
+}
+
+// checkDisabled fails logins to a disabled role.
+func checkDisabled(in *checkInput) error {
+	if !in.role.Disabled {
+		return nil
+	}
+	message := "the role is disabled"
+	if in.role.DisabledReason != "" {
+		message += ": " + in.role.DisabledReason
+	}
+	return newLoginError(errCodeRoleDisabled, message, nil)
+}
+
+const (
+	pathRoleDisableSyn  = `Disables a role without deleting it.`
+	pathRoleDisableDesc = `
+Denies logins to the role and renewals of its tokens until it is enabled
+again. The rest of the role is kept. The optional reason is returned to
+callers whose arn matches the role when their login is denied.
+`
+	pathRoleEnableSyn  = `Enables a disabled role.`
+	pathRoleEnableDesc = `
+Allows logins to the role and renewals of its tokens again.
+`
+)
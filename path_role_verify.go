@@ -0,0 +1,264 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/hashicorp/vault-plugin-auth-tencentcloud/clients"
+	"github.com/hashicorp/vault-plugin-auth-tencentcloud/tracing"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	verifyPass = "pass"
+	verifyFail = "fail"
+)
+
+// verifyStep is the outcome of one step of a dry-run login.
+type verifyStep struct {
+	Step   string `json:"step"`
+	Result string `json:"result"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func pathRoleVerify(b *backend) *framework.Path {
+	fields := loginFields()
+	fields["role"] = &framework.FieldSchema{
+		Type:        framework.TypeLowerCaseString,
+		Description: "Name of the role to verify.",
+	}
+	fields["caller_arn"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: "ARN of the caller, instead of credentials: an assumed-role arn, whose RoleId is resolved with CAM, " +
+			"or a CAM role arn.",
+	}
+	fields["account_id"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: "Account of a synthetic caller, instead of credentials.",
+	}
+	fields["cam_role_name"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: "CAM role assumed by a synthetic caller.",
+	}
+	fields["identity_type"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: "Identity type of a synthetic caller.",
+		Default:     "CAMRole",
+	}
+	fields["source_ip"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: "Address the caller logs in from.",
+	}
+	return &framework.Path{
+		Pattern: rolePath + framework.GenericNameRegex("role") + "/verify$",
+		Fields:  fields,
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathRoleVerifyUpdate,
+			},
+		},
+		HelpSynopsis:    pathRoleVerifySyn,
+		HelpDescription: pathRoleVerifyDesc,
+	}
+}
+
+// pathRoleVerifyUpdate
+func (b *backend) pathRoleVerifyUpdate(ctx context.Context,
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role").(string)
+	role, err := b.readRole(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("role %s not found", roleName)), nil
+	}
+	ip := data.Get("source_ip").(string)
+	if ip != "" && net.ParseIP(ip) == nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid source_ip %q", ip)), nil
+	}
+
+	ctx, span := tracing.Start(b.traceContext(ctx, req.Storage), "verify")
+	defer span.End()
+
+	var steps []*verifyStep
+	attempt := &loginAttempt{
+		onStage: func(name string, err error) {
+			step := &verifyStep{Step: name, Result: verifyPass}
+			if err != nil {
+				step.Result = verifyFail
+				step.Reason = err.Error()
+			}
+			steps = append(steps, step)
+		},
+	}
+	c, err := b.verifyCallerForDryRun(ctx, req, data, attempt)
+	var loginErr *loginError
+	if err != nil && !errors.As(err, &loginErr) {
+		return nil, err
+	}
+
+	allowed := err == nil
+	if c != nil {
+		in := newCheckInput(ctx, c, role, roleName, ip)
+		checks := []roleCheck{{
+			name: "check_lockout",
+			check: func(in *checkInput) error {
+				return b.checkLockout(ctx, req.Storage, c.principal)
+			},
+		}}
+		checks = append(checks, roleChecks...)
+		checks = append(checks, roleCheck{
+			name: "check_rate",
+			check: func(in *checkInput) error {
+				return b.checkLoginRate(role, roleName, c.principal, true)
+			},
+		})
+		// Unlike a login, every constraint is checked so that all failures
+		// are reported at once.
+		for _, check := range checks {
+			check := check
+			if err := attempt.stage(ctx, check.name, func(ctx context.Context, span *tracing.Span) error {
+				return check.check(in)
+			}); err != nil {
+				if !errors.As(err, &loginErr) {
+					return nil, err
+				}
+				allowed = false
+			}
+		}
+	}
+
+	respData := map[string]interface{}{
+		"role":    roleName,
+		"allowed": allowed,
+		"steps":   steps,
+	}
+	if c != nil {
+		respData["identity"] = map[string]interface{}{
+			"arn":           c.identity.Arn,
+			"account_id":    c.identity.AccountId,
+			"identity_type": c.identity.Type,
+			"cam_role_name": c.arn.RoleName,
+		}
+	}
+	return &logical.Response{Data: respData}, nil
+}
+
+// verifyCallerForDryRun builds the caller of a dry-run login from
+// credentials, a caller arn or a synthetic identity. It returns nil with a
+// *loginError when the caller cannot be established.
+func (b *backend) verifyCallerForDryRun(ctx context.Context, req *logical.Request,
+	data *framework.FieldData, attempt *loginAttempt) (*caller, error) {
+	callerARN := data.Get("caller_arn").(string)
+	accountID := data.Get("account_id").(string)
+	camRoleName := data.Get("cam_role_name").(string)
+	identityType := data.Get("identity_type").(string)
+	_, hasCreds := data.GetOk("secret_id")
+
+	sources := 0
+	for _, given := range []bool{hasCreds, callerARN != "", accountID != "" || camRoleName != ""} {
+		if given {
+			sources++
+		}
+	}
+	if sources != 1 {
+		err := newLoginError(errCodeInvalidRequest,
+			"exactly one of credentials, caller_arn, or account_id and cam_role_name is required", nil)
+		attempt.stage(ctx, "validate_input", func(ctx context.Context, span *tracing.Span) error {
+			return err
+		})
+		return nil, err
+	}
+
+	switch {
+	case hasCreds:
+		c, err := b.verifyCaller(ctx, req, data, attempt)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+
+	case callerARN != "":
+		api, err := b.readAPIConfig(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		c := &caller{}
+		c.camClient = func(ctx context.Context) (clients.CAMAPI, bool, error) {
+			client, err := b.serverCAMClient(ctx, req.Storage, api, c.arn.Uin)
+			return client, false, err
+		}
+		if err := attempt.stage(ctx, "parse_arn", func(ctx context.Context, span *tracing.Span) (err error) {
+			c.arn, err = parseARN(callerARN)
+			if err != nil {
+				return newLoginError(errCodeInvalidRequest, "unable to parse caller_arn", err)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		if c.arn.Type == arnAssumedRoleType {
+			if err := attempt.stage(ctx, "cam_lookup", func(ctx context.Context, span *tracing.Span) (err error) {
+				camClient, callerCreds, err := c.camClient(ctx)
+				if err != nil {
+					return newLoginError(errCodeUpstreamError, "unable to look up the caller's CAM role", err)
+				}
+				if c.arn.RoleName, err = camClient.GetRoleName(ctx, c.arn.RoleId); err != nil {
+					return upstreamLoginError("CAM", callerCreds, err)
+				}
+				return nil
+			}); err != nil {
+				return nil, err
+			}
+		}
+		c.identity = &clients.CallerIdentityRsp{
+			Arn:       callerARN,
+			AccountId: c.arn.Uin,
+			Type:      "CAMRole",
+		}
+		return c, nil
+
+	default:
+		c := &caller{
+			identity: &clients.CallerIdentityRsp{
+				AccountId: accountID,
+				Type:      identityType,
+			},
+			arn: &arn{
+				Uin:      accountID,
+				RoleName: camRoleName,
+				Type:     arnAssumedRoleType,
+			},
+		}
+		if err := attempt.stage(ctx, "identity_type", func(ctx context.Context, span *tracing.Span) error {
+			if accountID == "" || camRoleName == "" {
+				return newLoginError(errCodeInvalidRequest, "a synthetic caller needs account_id and cam_role_name", nil)
+			}
+			if identityType != "CAMRole" {
+				return newLoginError(errCodeUnsupportedIdentityType,
+					fmt.Sprintf("%s identities are not supported at this time", identityType), nil)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+}
+
+const (
+	pathRoleVerifySyn  = `Dry-runs a login to a role and reports each constraint.`
+	pathRoleVerifyDesc = `
+Evaluates a login to the role without issuing a token. The caller is given as
+credentials, which are verified like a login's, as a caller_arn, or as a
+synthetic identity made of account_id and cam_role_name. source_ip is checked
+against the role's bound CIDRs. Every step of the login is reported as pass or
+fail with the reason it failed; unlike a login, all of the role's constraints
+are checked even after one fails. check_lockout and check_rate do not count
+the dry run, and only apply to callers given as credentials.
+`
+)
@@ -0,0 +1,85 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault-plugin-auth-tencentcloud/clients"
+	"github.com/hashicorp/vault/sdk/helper/cidrutil"
+	"github.com/hashicorp/vault/sdk/helper/policyutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// verifyCAMRole checks with CAM that the role the caller assumed at login
+// still exists under the same name. A role that was deleted, or deleted and
+// recreated under the same name, has no role with the old RoleId. The role
+// name cache is bypassed so that a revocation takes effect at once.
+func (b *backend) verifyCAMRole(ctx context.Context, s logical.Storage, parsedARN *arn) error {
+	if parsedARN.RoleId == "" {
+		return fmt.Errorf("unable to verify %s with CAM: it has no role id", parsedARN)
+	}
+	api, err := b.readAPIConfig(ctx, s)
+	if err != nil {
+		return err
+	}
+	camClient, err := b.serverCAMClient(ctx, s, api, parsedARN.Uin)
+	if err != nil {
+		return errwrap.Wrapf("unable to verify the CAM role: {{err}}", err)
+	}
+	roleName, err := camClient.GetRoleName(ctx, parsedARN.RoleId)
+	if clients.IsRoleNotFound(err) {
+		return fmt.Errorf("CAM role %s (%s) no longer exists", parsedARN.RoleName, parsedARN.RoleId)
+	}
+	if err != nil {
+		return errwrap.Wrapf("unable to verify the CAM role: {{err}}", err)
+	}
+	if roleName != parsedARN.RoleName {
+		return fmt.Errorf("CAM role %s is now named %s", parsedARN.RoleId, roleName)
+	}
+	return nil
+}
+
+// serverCAMClient returns a CAM client for lookups in the account uin made
+// without the caller's credentials. Only the matching account config is used:
+// there is no fallback to the environment or the CVM role.
+func (b *backend) serverCAMClient(ctx context.Context, s logical.Storage, api *apiConfig, uin string) (clients.CAMAPI, error) {
+	client, err := b.accountCAMClient(ctx, s, api, uin)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, fmt.Errorf("no config/account entry is configured for account %s", uin)
+	}
+	return client, nil
+}
+
+// checkPolicyDrift fails the renewal of a token whose policies differ from
+// the role's current token_policies. Vault keeps the policies a token was
+// issued with, so the caller has to log in again to get the new ones.
+func checkPolicyDrift(auth *logical.Auth, role *roleEntry, roleName string) error {
+	tokenPolicies := auth.TokenPolicies
+	if len(tokenPolicies) == 0 {
+		tokenPolicies = auth.Policies
+	}
+	if policyutil.EquivalentPolicies(tokenPolicies, role.TokenPolicies) {
+		return nil
+	}
+	return fmt.Errorf("policies of role %s have changed, log in again to get a token with the new policies", roleName)
+}
+
+// checkRenewalCIDRs checks the renewing client against the role's current
+// bound CIDRs, which may have changed since login.
+func checkRenewalCIDRs(req *logical.Request, role *roleEntry) error {
+	if len(role.TokenBoundCIDRs) == 0 {
+		return nil
+	}
+	if req.Connection == nil {
+		return errors.New("the role has bound CIDRs but the source address could not be verified")
+	}
+	if !cidrutil.RemoteAddrIsOk(req.Connection.RemoteAddr, role.TokenBoundCIDRs) {
+		return fmt.Errorf("%s is not in the bound CIDRs of the role, not renewing", req.Connection.RemoteAddr)
+	}
+	return nil
+}
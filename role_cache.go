@@ -0,0 +1,69 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"sync"
+)
+
+// roleCache holds decoded role entries so logins and renewals do not read and
+// decode storage every time. Entries are filled on read, replaced on write and
+// dropped on delete or when storage invalidates the key. Every change bumps
+// the generation, so an entry read from storage concurrently with a change is
+// not cached.
+type roleCache struct {
+	lock    sync.RWMutex
+	gen     uint64
+	entries map[string]*roleEntry
+}
+
+func newRoleCache() *roleCache {
+	return &roleCache{
+		entries: make(map[string]*roleEntry),
+	}
+}
+
+// get returns a copy of the cached entry. Callers may replace its fields but
+// must not modify its slices or pointers in place.
+func (c *roleCache) get(name string) (*roleEntry, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	role, ok := c.entries[name]
+	if !ok {
+		return nil, false
+	}
+	copied := *role
+	return &copied, true
+}
+
+// generation must be read before the storage read an entry is filled from.
+func (c *roleCache) generation() uint64 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.gen
+}
+
+// fill caches an entry read from storage unless the role changed meanwhile.
+func (c *roleCache) fill(gen uint64, name string, role *roleEntry) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if gen == c.gen {
+		copied := *role
+		c.entries[name] = &copied
+	}
+}
+
+// set caches an entry that was just written.
+func (c *roleCache) set(name string, role *roleEntry) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.gen++
+	copied := *role
+	c.entries[name] = &copied
+}
+
+// remove drops the entry so the next read goes to storage.
+func (c *roleCache) remove(name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.gen++
+	delete(c.entries, name)
+}
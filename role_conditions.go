@@ -0,0 +1,159 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault-plugin-auth-tencentcloud/conditions"
+)
+
+// Attributes of the caller that role conditions can use.
+const (
+	condAccountID   = "account_id"
+	condARN         = "arn"
+	condPrincipalID = "principal_id"
+	condUserID      = "user_id"
+	condSessionName = "session_name"
+	condCAMRoleName = "cam_role_name"
+	condRegion      = "region"
+	condSTSRegion   = "sts_region"
+	condSourceIP    = "source_ip"
+	condHour        = "hour"
+	condMinute      = "minute"
+	condWeekday     = "weekday"
+	condTime        = "time"
+	condTags        = "tags"
+)
+
+var conditionAttributes = []string{
+	condAccountID, condARN, condPrincipalID, condUserID, condSessionName, condCAMRoleName,
+	condRegion, condSTSRegion, condSourceIP, condHour, condMinute, condWeekday, condTime, condTags,
+}
+
+// parseConditions parses the conditions of a role and checks that they only
+// use known attributes.
+func parseConditions(exprs []string) ([]*conditions.Expr, error) {
+	parsed := make([]*conditions.Expr, 0, len(exprs))
+	for _, src := range exprs {
+		expr, err := conditions.Parse(src)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition %q: %s", src, err)
+		}
+		for _, name := range expr.Attributes() {
+			if !isConditionAttribute(name) {
+				return nil, fmt.Errorf("invalid condition %q: unknown attribute %q", src, name)
+			}
+		}
+		parsed = append(parsed, expr)
+	}
+	return parsed, nil
+}
+
+// parsedConditions returns the role's conditions as parsed when the role was
+// written or read from storage, parsing them if the entry was built otherwise.
+func (r *roleEntry) parsedConditions() ([]*conditions.Expr, error) {
+	if len(r.conditions) == len(r.Conditions) {
+		return r.conditions, nil
+	}
+	return parseConditions(r.Conditions)
+}
+
+func isConditionAttribute(name string) bool {
+	for _, known := range conditionAttributes {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConditions checks that the caller meets every condition of the role.
+// Conditions that fail to evaluate deny the login.
+func checkConditions(in *checkInput) error {
+	if len(in.role.Conditions) == 0 {
+		return nil
+	}
+	exprs, err := in.role.parsedConditions()
+	if err != nil {
+		return err
+	}
+	attrs := conditionAttributesOf(in)
+	for _, expr := range exprs {
+		if expr.Uses(condTags) && attrs[condTags] == nil {
+			tags, err := in.tags()
+			if err != nil {
+				var loginErr *loginError
+				if errors.As(err, &loginErr) {
+					return err
+				}
+				return upstreamLoginError("CAM", false, err)
+			}
+			attrs[condTags] = tags
+		}
+		ok, err := expr.Eval(attrs)
+		if err != nil {
+			return newLoginError(errCodeConditionFailed, "the caller does not meet the role's conditions",
+				fmt.Errorf("condition %q of role %s failed: %s", expr, in.roleName, err))
+		}
+		if !ok {
+			return newLoginError(errCodeConditionFailed, "the caller does not meet the role's conditions",
+				fmt.Errorf("condition %q of role %s is false", expr, in.roleName))
+		}
+	}
+	return nil
+}
+
+// conditionAttributesOf returns the attributes of the caller, except for the
+// tags of its CAM role, which are only read when a condition uses them.
+func conditionAttributesOf(in *checkInput) conditions.Attributes {
+	identity := in.caller.identity
+	sessionName := ""
+	if i := strings.Index(identity.UserId, ":"); i >= 0 {
+		sessionName = identity.UserId[i+1:]
+	}
+	now := in.now.UTC()
+	return conditions.Attributes{
+		condAccountID:   identity.AccountId,
+		condARN:         identity.Arn,
+		condPrincipalID: identity.PrincipalId,
+		condUserID:      identity.UserId,
+		condSessionName: sessionName,
+		condCAMRoleName: in.caller.arn.RoleName,
+		condRegion:      in.caller.region,
+		condSTSRegion:   in.caller.stsRegion,
+		condSourceIP:    in.sourceIP,
+		condHour:        int64(now.Hour()),
+		condMinute:      int64(now.Minute()),
+		condWeekday:     now.Weekday().String(),
+		condTime:        now.Format("15:04"),
+	}
+}
+
+// roleTagsLoader returns a function that reads the tags of the caller's CAM
+// role at most once.
+func roleTagsLoader(ctx context.Context, c *caller) func() (map[string]string, error) {
+	var tags map[string]string
+	var err error
+	loaded := false
+	return func() (map[string]string, error) {
+		if loaded {
+			return tags, err
+		}
+		loaded = true
+		if c.camClient == nil || c.arn.RoleId == "" {
+			err = newLoginError(errCodeConditionFailed, "the tags of the caller's CAM role are not known", nil)
+			return nil, err
+		}
+		client, callerCreds, clientErr := c.camClient(ctx)
+		if clientErr != nil {
+			err = clientErr
+			return nil, err
+		}
+		if tags, err = client.GetRoleTags(ctx, c.arn.RoleId); err != nil {
+			err = upstreamLoginError("CAM", callerCreds, err)
+		}
+		return tags, err
+	}
+}
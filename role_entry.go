@@ -4,9 +4,16 @@ import (
 	"time"
 
 	"github.com/hashicorp/go-sockaddr"
+	"github.com/hashicorp/vault-plugin-auth-tencentcloud/conditions"
 	"github.com/hashicorp/vault/sdk/helper/tokenutil"
 )
 
+// Values of renewal_verification.
+const (
+	renewalVerificationNone = "none"
+	renewalVerificationCAM  = "cam"
+)
+
 type roleEntry struct {
 	tokenutil.TokenParams
 	ARN        *arn                          `json:"arn"`
@@ -15,6 +22,39 @@ type roleEntry struct {
 	MaxTTL     time.Duration                 `json:"max_ttl"`
 	Period     time.Duration                 `json:"period"`
 	BoundCIDRs []*sockaddr.SockAddrMarshaler `json:"bound_cidrs"`
+
+	// RenewalVerification is how the caller's CAM role is checked again when
+	// a token is renewed: not at all, or with CAM.
+	RenewalVerification string `json:"renewal_verification"`
+	// Conditions are expressions over the caller's attributes that must all
+	// be true for a login to succeed.
+	Conditions []string `json:"conditions"`
+	// conditions are the parsed Conditions. Cached entries share them, so
+	// they must not be modified.
+	conditions []*conditions.Expr
+
+	// NotBefore and NotAfter, if set, bound when logins to the role are
+	// allowed. Tokens expire no later than NotAfter.
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	// LoginWindows are the recurring windows logins are allowed in, in the
+	// time zone LoginWindowTimeZone. If empty, logins are allowed at any time.
+	LoginWindows        []string `json:"allowed_login_windows"`
+	LoginWindowTimeZone string   `json:"login_window_time_zone"`
+	// loginWindows and loginLocation are the parsed LoginWindows and
+	// LoginWindowTimeZone, shared like conditions.
+	loginWindows  []*loginWindow
+	loginLocation *time.Location
+
+	// Disabled roles deny logins and renewals, for DisabledReason, since
+	// DisabledAt.
+	Disabled       bool      `json:"disabled"`
+	DisabledReason string    `json:"disabled_reason"`
+	DisabledAt     time.Time `json:"disabled_at"`
+
+	// MaxLoginsPerMinute caps the successful logins of each CAM role, over all
+	// of its sessions, to the role per minute. 0 is unlimited.
+	MaxLoginsPerMinute int `json:"max_logins_per_minute"`
 }
 
 // ToResponseData
@@ -24,7 +64,23 @@ func (r *roleEntry) ToResponseData() map[string]interface{} {
 		cidrs[i] = cidr.String()
 	}
 	d := map[string]interface{}{
-		"arn": r.ARN.String(),
+		"arn":                    r.ARN.String(),
+		"renewal_verification":   r.renewalVerification(),
+		"conditions":             r.Conditions,
+		"not_before":             formatRoleTime(r.NotBefore),
+		"not_after":              formatRoleTime(r.NotAfter),
+		"allowed_login_windows":  r.LoginWindows,
+		"login_window_time_zone": r.loginWindowTimeZone(),
+		"disabled":               r.Disabled,
+		"disabled_reason":        r.DisabledReason,
+		"disabled_at":            formatRoleTime(r.DisabledAt),
+		"max_logins_per_minute":  r.MaxLoginsPerMinute,
+	}
+	if r.Conditions == nil {
+		d["conditions"] = []string{}
+	}
+	if r.LoginWindows == nil {
+		d["allowed_login_windows"] = []string{}
 	}
 	r.PopulateTokenData(d)
 	if len(r.Policies) > 0 {
@@ -44,3 +100,27 @@ func (r *roleEntry) ToResponseData() map[string]interface{} {
 	}
 	return d
 }
+
+// renewalVerification returns RenewalVerification, defaulting to none.
+func (r *roleEntry) renewalVerification() string {
+	if r.RenewalVerification == "" {
+		return renewalVerificationNone
+	}
+	return r.RenewalVerification
+}
+
+// loginWindowTimeZone returns LoginWindowTimeZone, defaulting to UTC.
+func (r *roleEntry) loginWindowTimeZone() string {
+	if r.LoginWindowTimeZone == "" {
+		return "UTC"
+	}
+	return r.LoginWindowTimeZone
+}
+
+// formatRoleTime formats t as RFC 3339, or the zero time as "".
+func formatRoleTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,97 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// arnIndexPrefix holds one empty entry per Vault role and bound CAM role,
+	// at arnIndexPrefix<uin>/<CAM role name>/<Vault role name>. Each binding
+	// is its own key, so roles are indexed without read-modify-write races.
+	arnIndexPrefix = "index/arn/"
+	// arnIndexVersionKey records that roles saved before the index existed
+	// have been indexed.
+	arnIndexVersionKey = "index/arn-version"
+	arnIndexVersion    = "1"
+)
+
+// arnIndexKey returns the index key binding a CAM role arn to a Vault role.
+func arnIndexKey(a *arn, roleName string) string {
+	return arnIndexPrefix + a.Uin + "/" + a.RoleName + "/" + roleName
+}
+
+// updateARNIndex moves the index entry of a Vault role from the arn it was
+// bound to, if any, to the arn it is bound to now, if any.
+func updateARNIndex(ctx context.Context, s logical.Storage, roleName string, old, new *arn) error {
+	if old != nil && (new == nil || arnIndexKey(old, roleName) != arnIndexKey(new, roleName)) {
+		if err := s.Delete(ctx, arnIndexKey(old, roleName)); err != nil {
+			return err
+		}
+	}
+	if new != nil {
+		if err := s.Put(ctx, &logical.StorageEntry{Key: arnIndexKey(new, roleName)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rolesForARN returns the Vault roles bound to the CAM role camRoleName of
+// account uin, in the order login prefers them: the Vault role named like
+// the CAM role first, then the others by name.
+func rolesForARN(ctx context.Context, s logical.Storage, uin, camRoleName string) ([]string, error) {
+	if uin == "" || camRoleName == "" || strings.Contains(uin+camRoleName, "/") {
+		return nil, nil
+	}
+	names, err := s.List(ctx, arnIndexPrefix+uin+"/"+camRoleName+"/")
+	if err != nil {
+		return nil, err
+	}
+	// Vault role names are lowercase, CAM role names may not be.
+	sort.Slice(names, func(i, j int) bool {
+		iNamed, jNamed := strings.EqualFold(names[i], camRoleName), strings.EqualFold(names[j], camRoleName)
+		if iNamed != jNamed {
+			return iNamed
+		}
+		return names[i] < names[j]
+	})
+	return names, nil
+}
+
+// initialize indexes the roles saved before the arn index existed, and warns
+// about settings that are no longer used.
+func (b *backend) initialize(ctx context.Context, req *logical.InitializationRequest) error {
+	if err := b.warnLegacyClientConfig(ctx, req.Storage); err != nil {
+		return err
+	}
+	entry, err := req.Storage.Get(ctx, arnIndexVersionKey)
+	if err != nil {
+		return err
+	}
+	if entry != nil && string(entry.Value) == arnIndexVersion {
+		return nil
+	}
+	names, err := req.Storage.List(ctx, rolePath)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		role, err := readStoredRole(ctx, req.Storage, name)
+		if err != nil {
+			return errwrap.Wrapf("unable to index role "+name+": {{err}}", err)
+		}
+		if role == nil || role.ARN == nil {
+			continue
+		}
+		if err := updateARNIndex(ctx, req.Storage, name, nil, role.ARN); err != nil {
+			return errwrap.Wrapf("unable to index role "+name+": {{err}}", err)
+		}
+	}
+	b.Logger().Info("indexed roles by arn", "roles", len(names))
+	return req.Storage.Put(ctx, &logical.StorageEntry{Key: arnIndexVersionKey, Value: []byte(arnIndexVersion)})
+}
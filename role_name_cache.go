@@ -0,0 +1,136 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"context"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/hashicorp/vault-plugin-auth-tencentcloud/clients"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/sync/singleflight"
+)
+
+// roleNameLookupTimeout bounds a CAM lookup shared by concurrent logins.
+const roleNameLookupTimeout = 30 * time.Second
+
+// roleNameCache remembers which CAM role name belongs to a RoleId. Lookups
+// for the same RoleId that run concurrently share a single CAM call. Roles
+// that do not exist are remembered for negativeTTL.
+type roleNameCache struct {
+	ttl         time.Duration
+	negativeTTL time.Duration
+	entries     *lru.Cache
+	group       singleflight.Group
+}
+
+type roleNameCacheEntry struct {
+	roleName   string
+	err        error
+	expiration time.Time
+}
+
+func newRoleNameCache(config *cacheConfig) (*roleNameCache, error) {
+	entries, err := lru.New(config.RoleCacheSize)
+	if err != nil {
+		return nil, err
+	}
+	return &roleNameCache{
+		ttl:         config.RoleCacheTTL,
+		negativeTTL: config.RoleCacheNegativeTTL,
+		entries:     entries,
+	}, nil
+}
+
+// get returns the role name for key, calling lookup on a miss. Concurrent
+// misses of key share one lookup, whoever's credentials it is made with. The
+// lookup runs detached from the cancellation of whichever request started
+// it, within roleNameLookupTimeout.
+func (c *roleNameCache) get(ctx context.Context, key string,
+	lookup func(ctx context.Context) (string, error)) (string, error) {
+	if raw, ok := c.entries.Get(key); ok {
+		entry := raw.(*roleNameCacheEntry)
+		if time.Now().Before(entry.expiration) {
+			return entry.roleName, entry.err
+		}
+		c.entries.Remove(key)
+	}
+
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		lookupCtx, cancel := context.WithTimeout(detachedContext{ctx}, roleNameLookupTimeout)
+		defer cancel()
+		roleName, err := lookup(lookupCtx)
+		c.store(key, roleName, err)
+		return roleName, err
+	})
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case result := <-ch:
+		if result.Err != nil {
+			return "", result.Err
+		}
+		return result.Val.(string), nil
+	}
+}
+
+// detachedContext carries the values of a context, such as its trace span,
+// but not its deadline or cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (c detachedContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (c detachedContext) Done() <-chan struct{}             { return nil }
+func (c detachedContext) Err() error                        { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// store caches a successful lookup for ttl and a missing role for negativeTTL.
+// Other failures, such as throttling, are not cached.
+func (c *roleNameCache) store(key, roleName string, err error) {
+	ttl := c.ttl
+	if err != nil {
+		if !clients.IsRoleNotFound(err) {
+			return
+		}
+		ttl = c.negativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	c.entries.Add(key, &roleNameCacheEntry{
+		roleName:   roleName,
+		err:        err,
+		expiration: time.Now().Add(ttl),
+	})
+}
+
+// getRoleNameCache returns the cache, building it from config/cache on first use.
+func (b *backend) getRoleNameCache(ctx context.Context, s logical.Storage) (*roleNameCache, error) {
+	b.roleNamesLock.RLock()
+	cache := b.roleNames
+	b.roleNamesLock.RUnlock()
+	if cache != nil {
+		return cache, nil
+	}
+
+	b.roleNamesLock.Lock()
+	defer b.roleNamesLock.Unlock()
+	if b.roleNames != nil {
+		return b.roleNames, nil
+	}
+	config, err := readCacheConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if b.roleNames, err = newRoleNameCache(config); err != nil {
+		return nil, err
+	}
+	return b.roleNames, nil
+}
+
+// resetRoleNameCache drops the cache so the next login rebuilds it from config.
+func (b *backend) resetRoleNameCache() {
+	b.roleNamesLock.Lock()
+	defer b.roleNamesLock.Unlock()
+	b.roleNames = nil
+}
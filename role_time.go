@@ -0,0 +1,220 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// loginWindow is a recurring period in which logins are allowed, written as
+// "<days> <start>-<end>", e.g. "Mon-Fri 09:00-17:30" or "Sat,Sun 22:00-02:00".
+// A window whose end is not after its start runs past midnight and belongs
+// to the day it starts on.
+type loginWindow struct {
+	days [7]bool
+	// start and end are minutes since midnight.
+	start, end int
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parseLoginWindow parses a window such as "Mon-Fri 09:00-17:30". Days are
+// a comma-separated list of days and day ranges, or "*" for every day.
+func parseLoginWindow(s string) (*loginWindow, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("invalid login window %q, expected e.g. \"Mon-Fri 09:00-17:30\"", s)
+	}
+	w := &loginWindow{}
+	if fields[0] == "*" {
+		for i := range w.days {
+			w.days[i] = true
+		}
+	} else {
+		for _, days := range strings.Split(fields[0], ",") {
+			first, last := days, days
+			if i := strings.Index(days, "-"); i >= 0 {
+				first, last = days[:i], days[i+1:]
+			}
+			from, ok := weekdays[strings.ToLower(first)]
+			if !ok {
+				return nil, fmt.Errorf("invalid day %q in login window %q", first, s)
+			}
+			to, ok := weekdays[strings.ToLower(last)]
+			if !ok {
+				return nil, fmt.Errorf("invalid day %q in login window %q", last, s)
+			}
+			for day := from; ; day = (day + 1) % 7 {
+				w.days[day] = true
+				if day == to {
+					break
+				}
+			}
+		}
+	}
+
+	hours := strings.Split(fields[1], "-")
+	if len(hours) != 2 {
+		return nil, fmt.Errorf("invalid hours %q in login window %q", fields[1], s)
+	}
+	var err error
+	if w.start, err = parseClock(hours[0]); err != nil {
+		return nil, fmt.Errorf("invalid login window %q: %s", s, err)
+	}
+	if w.end, err = parseClock(hours[1]); err != nil {
+		return nil, fmt.Errorf("invalid login window %q: %s", s, err)
+	}
+	if w.start == w.end {
+		return nil, fmt.Errorf("login window %q is empty", s)
+	}
+	return w, nil
+}
+
+// parseClock parses a time of day as HH:MM, where 24:00 is the end of the day.
+func parseClock(s string) (int, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 || len(parts[1]) != 2 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	if hour < 0 || minute < 0 || minute > 59 || hour*60+minute > 24*60 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// contains reports whether t, in the time zone of the window, is in the window.
+func (w *loginWindow) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	if w.start < w.end {
+		return w.days[day] && minute >= w.start && minute < w.end
+	}
+	yesterday := (day + 6) % 7
+	return (w.days[day] && minute >= w.start) || (w.days[yesterday] && minute < w.end)
+}
+
+// parseLoginWindows parses the login windows of a role.
+func parseLoginWindows(windows []string) ([]*loginWindow, error) {
+	parsed := make([]*loginWindow, 0, len(windows))
+	for _, s := range windows {
+		w, err := parseLoginWindow(s)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, w)
+	}
+	return parsed, nil
+}
+
+// parseRoleLoginWindows parses the login windows of a role and their time zone.
+func parseRoleLoginWindows(role *roleEntry) ([]*loginWindow, *time.Location, error) {
+	windows, err := parseLoginWindows(role.LoginWindows)
+	if err != nil {
+		return nil, nil, err
+	}
+	location, err := time.LoadLocation(role.loginWindowTimeZone())
+	if err != nil {
+		return nil, nil, err
+	}
+	return windows, location, nil
+}
+
+// parseRoleTime parses an RFC 3339 time. The empty string is the zero time.
+func parseRoleTime(field, s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q, expected an RFC 3339 time", field, s)
+	}
+	return t.UTC(), nil
+}
+
+// checkLoginTime checks that the role is valid at the time of the login and
+// that the login falls in one of the role's login windows.
+func checkLoginTime(in *checkInput) error {
+	role := in.role
+	if !role.NotBefore.IsZero() && in.now.Before(role.NotBefore) {
+		return newLoginError(errCodeLoginTimeDenied, "the role is not valid yet",
+			fmt.Errorf("role %s is not valid before %s", in.roleName, role.NotBefore.Format(time.RFC3339)))
+	}
+	if !role.NotAfter.IsZero() && !in.now.Before(role.NotAfter) {
+		return newLoginError(errCodeLoginTimeDenied, "the role has expired",
+			fmt.Errorf("role %s expired at %s", in.roleName, role.NotAfter.Format(time.RFC3339)))
+	}
+	if len(role.LoginWindows) == 0 {
+		return nil
+	}
+	windows, location := role.loginWindows, role.loginLocation
+	if location == nil {
+		// Only entries that were not written or read from storage lack them.
+		var err error
+		if windows, location, err = parseRoleLoginWindows(role); err != nil {
+			return err
+		}
+	}
+	now := in.now.In(location)
+	for _, w := range windows {
+		if w.contains(now) {
+			return nil
+		}
+	}
+	return newLoginError(errCodeLoginTimeDenied, "logins to the role are not allowed at this time",
+		fmt.Errorf("%s is outside the login windows of role %s", now.Format("Mon 15:04 MST"), in.roleName))
+}
+
+// capTokenLifetime caps the lifetime of a token issued at issueTime so that
+// it expires no later than the role's not_after.
+func capTokenLifetime(auth *logical.Auth, role *roleEntry, issueTime time.Time) {
+	if role.NotAfter.IsZero() {
+		return
+	}
+	remaining := role.NotAfter.Sub(issueTime)
+	if auth.MaxTTL == 0 || auth.MaxTTL > remaining {
+		auth.MaxTTL = remaining
+	}
+	if auth.ExplicitMaxTTL == 0 || auth.ExplicitMaxTTL > remaining {
+		auth.ExplicitMaxTTL = remaining
+	}
+	if auth.TTL > remaining {
+		auth.TTL = remaining
+	}
+}
+
+// checkRenewalTime fails the renewal of a token of a role that has expired,
+// and caps its lifetime at the role's current not_after.
+func checkRenewalTime(auth *logical.Auth, role *roleEntry, now time.Time) error {
+	if role.NotAfter.IsZero() {
+		return nil
+	}
+	if !now.Before(role.NotAfter) {
+		return errors.New("the role has expired, not renewing")
+	}
+	issueTime := auth.IssueTime
+	if issueTime.IsZero() {
+		issueTime = now
+	}
+	capTokenLifetime(auth, role, issueTime)
+	return nil
+}
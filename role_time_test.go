@@ -0,0 +1,53 @@
+package vault_plugin_auth_tencentcloud
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginWindow(t *testing.T) {
+	at := func(s string) time.Time {
+		t.Helper()
+		tm, err := time.Parse("Mon 2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	tests := []struct {
+		window string
+		time   string
+		want   bool
+	}{
+		{"Mon-Fri 09:00-17:30", "Mon 2024-01-01 09:00", true},
+		{"Mon-Fri 09:00-17:30", "Fri 2024-01-05 17:29", true},
+		{"Mon-Fri 09:00-17:30", "Fri 2024-01-05 17:30", false},
+		{"Mon-Fri 09:00-17:30", "Sat 2024-01-06 12:00", false},
+		{"sat,SUN 00:00-24:00", "Sun 2024-01-07 23:59", true},
+		{"Fri-Mon 10:00-11:00", "Sun 2024-01-07 10:30", true},
+		{"Fri-Mon 10:00-11:00", "Wed 2024-01-03 10:30", false},
+		// Windows that run past midnight belong to the day they start on.
+		{"Fri 22:00-02:00", "Fri 2024-01-05 23:00", true},
+		{"Fri 22:00-02:00", "Sat 2024-01-06 01:59", true},
+		{"Fri 22:00-02:00", "Sat 2024-01-06 23:00", false},
+		{"* 22:00-02:00", "Wed 2024-01-03 01:00", true},
+	}
+	for _, tt := range tests {
+		w, err := parseLoginWindow(tt.window)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.window, err)
+		}
+		if got := w.contains(at(tt.time)); got != tt.want {
+			t.Fatalf("%s at %s: got %t, want %t", tt.window, tt.time, got, tt.want)
+		}
+	}
+
+	for _, window := range []string{
+		"", "Mon-Fri", "Mon-Fri 09:00", "Mon-Fry 09:00-17:00", "Mon 9-17", "Mon 09:00-24:01",
+		"Mon 09:60-10:00", "Mon 09:00-09:00", "Mon 09:00-10:00 UTC",
+	} {
+		if _, err := parseLoginWindow(window); err == nil {
+			t.Fatalf("%q: expected an error", window)
+		}
+	}
+}
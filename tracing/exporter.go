@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// InMemoryExporter keeps finished spans in memory. It is meant for tests.
+type InMemoryExporter struct {
+	lock  sync.Mutex
+	spans []*SpanData
+}
+
+var _ Exporter = (*InMemoryExporter)(nil)
+
+// NewInMemoryExporter returns an empty exporter.
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// ExportSpan stores the span.
+func (e *InMemoryExporter) ExportSpan(span *SpanData) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Spans returns the spans exported so far, in the order they ended.
+func (e *InMemoryExporter) Spans() []*SpanData {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return append([]*SpanData(nil), e.spans...)
+}
+
+// Reset drops the stored spans.
+func (e *InMemoryExporter) Reset() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.spans = nil
+}
+
+// ExporterFunc adapts a function to the Exporter interface, e.g. to forward
+// spans to an OpenTelemetry or logging pipeline.
+type ExporterFunc func(span *SpanData)
+
+// ExportSpan calls f.
+func (f ExporterFunc) ExportSpan(span *SpanData) {
+	f(span)
+}
+
+// NewLogExporter returns an exporter that writes each span to logger at info
+// level, with its ids, duration, status and attributes.
+func NewLogExporter(logger hclog.Logger) Exporter {
+	return ExporterFunc(func(span *SpanData) {
+		args := []interface{}{
+			"name", span.Name,
+			"trace_id", span.TraceID,
+			"span_id", span.SpanID,
+			"parent_span_id", span.ParentSpanID,
+			"duration", span.Duration().String(),
+			"status", span.Status,
+		}
+		if span.StatusMessage != "" {
+			args = append(args, "error", span.StatusMessage)
+		}
+		keys := make([]string, 0, len(span.Attributes))
+		for key := range span.Attributes {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			args = append(args, "attribute."+key, span.Attributes[key])
+		}
+		logger.Info("span", args...)
+	})
+}
@@ -0,0 +1,224 @@
+// Package tracing records OpenTelemetry-style spans without depending on the
+// OpenTelemetry SDK. Spans started from a context become children of the
+// span already in it, and finished spans are handed to the exporter set on
+// the context with ContextWithExporter, or else to the one registered with
+// SetExporter. Without an exporter, spans cost almost nothing and are
+// dropped.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status codes of a finished span.
+const (
+	StatusUnset = "unset"
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// SpanData is a finished span, as handed to exporters.
+type SpanData struct {
+	Name         string
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Start        time.Time
+	End          time.Time
+	Attributes   map[string]string
+	Status       string
+	// StatusMessage describes the error of a span with StatusError.
+	StatusMessage string
+}
+
+// Duration returns how long the span lasted.
+func (d *SpanData) Duration() time.Duration {
+	return d.End.Sub(d.Start)
+}
+
+// Exporter receives finished spans. ExportSpan must not block for long,
+// since it is called on the traced code path.
+type Exporter interface {
+	ExportSpan(span *SpanData)
+}
+
+var (
+	exporterLock sync.RWMutex
+	exporter     Exporter
+)
+
+// SetExporter registers the exporter finished spans are sent to. A nil
+// exporter disables tracing.
+func SetExporter(e Exporter) {
+	exporterLock.Lock()
+	defer exporterLock.Unlock()
+	exporter = e
+}
+
+func currentExporter() Exporter {
+	exporterLock.RLock()
+	defer exporterLock.RUnlock()
+	return exporter
+}
+
+type exporterContextKey struct{}
+
+// ContextWithExporter returns a context whose spans, and their children, are
+// sent to e instead of the exporter registered with SetExporter.
+func ContextWithExporter(ctx context.Context, e Exporter) context.Context {
+	return context.WithValue(ctx, exporterContextKey{}, e)
+}
+
+// Span is a span being recorded. A nil *Span is valid and records nothing.
+type Span struct {
+	lock     sync.Mutex
+	data     SpanData
+	exporter Exporter
+	ended    bool
+}
+
+type spanContextKey struct{}
+
+// remoteParent is a parent span propagated from another process.
+type remoteParent struct {
+	traceID string
+	spanID  string
+}
+
+// Start starts a span that is a child of the span in ctx, or of the remote
+// parent set with ContextWithTraceParent, and returns a context holding it.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	e, _ := ctx.Value(exporterContextKey{}).(Exporter)
+	if e == nil {
+		e = currentExporter()
+	}
+	if e == nil {
+		return ctx, nil
+	}
+	span := &Span{
+		exporter: e,
+		data: SpanData{
+			Name:       name,
+			SpanID:     randomHex(8),
+			Start:      time.Now(),
+			Attributes: make(map[string]string),
+			Status:     StatusUnset,
+		},
+	}
+	switch parent := ctx.Value(spanContextKey{}).(type) {
+	case *Span:
+		span.data.TraceID = parent.data.TraceID
+		span.data.ParentSpanID = parent.data.SpanID
+	case *remoteParent:
+		span.data.TraceID = parent.traceID
+		span.data.ParentSpanID = parent.spanID
+	default:
+		span.data.TraceID = randomHex(16)
+	}
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// SpanFromContext returns the span in ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// ContextWithTraceParent returns a context whose spans continue the trace of
+// a W3C traceparent header, e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.
+// ctx is returned unchanged if it already holds a span or the header is malformed.
+func ContextWithTraceParent(ctx context.Context, traceparent string) context.Context {
+	if traceparent == "" || ctx.Value(spanContextKey{}) != nil {
+		return ctx
+	}
+	fields := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(fields) < 4 || len(fields[0]) != 2 || fields[0] == "ff" ||
+		!isHex(fields[1], 32) || !isHex(fields[2], 16) {
+		return ctx
+	}
+	if strings.Trim(fields[1], "0") == "" || strings.Trim(fields[2], "0") == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, spanContextKey{}, &remoteParent{
+		traceID: strings.ToLower(fields[1]),
+		spanID:  strings.ToLower(fields[2]),
+	})
+}
+
+// SetAttribute records a key/value pair describing the span.
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.data.Attributes[key] = value
+}
+
+// SetError marks the span as failed. A nil err marks it as succeeded.
+func (s *Span) SetError(err error) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err == nil {
+		s.data.Status, s.data.StatusMessage = StatusOK, ""
+		return
+	}
+	s.data.Status, s.data.StatusMessage = StatusError, err.Error()
+}
+
+// End finishes the span and exports it. Only the first call has an effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	if s.ended {
+		s.lock.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	data.Attributes = make(map[string]string, len(s.data.Attributes))
+	for k, v := range s.data.Attributes {
+		data.Attributes[k] = v
+	}
+	s.lock.Unlock()
+	s.exporter.ExportSpan(&data)
+}
+
+// TraceID returns the id of the span's trace, or "" for a nil span.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.data.TraceID
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		// Ids only need to be unique enough to tell spans apart.
+		now := time.Now().UnixNano()
+		for i := range b {
+			b[i] = byte(now >> (8 * uint(i%8)))
+		}
+	}
+	return hex.EncodeToString(b)
+}
+
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
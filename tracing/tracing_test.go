@@ -0,0 +1,116 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestStart(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	SetExporter(exporter)
+	defer SetExporter(nil)
+
+	ctx, root := Start(context.Background(), "root")
+	childCtx, child := Start(ctx, "child")
+	if SpanFromContext(childCtx) != child {
+		t.Fatal("expected the child span in its context")
+	}
+	child.SetAttribute("region", "ap-guangzhou")
+	child.SetError(errors.New("boom"))
+	child.End()
+	child.End()
+	root.SetError(nil)
+	root.End()
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	gotChild, gotRoot := spans[0], spans[1]
+	if gotChild.TraceID != gotRoot.TraceID || gotChild.ParentSpanID != gotRoot.SpanID || gotRoot.ParentSpanID != "" {
+		t.Fatalf("unexpected span ids: child %#v root %#v", gotChild, gotRoot)
+	}
+	if gotChild.Status != StatusError || gotChild.StatusMessage != "boom" || gotChild.Attributes["region"] != "ap-guangzhou" {
+		t.Fatalf("unexpected child: %#v", gotChild)
+	}
+	if gotRoot.Status != StatusOK || gotRoot.Duration() < gotChild.Duration() {
+		t.Fatalf("unexpected root: %#v", gotRoot)
+	}
+}
+
+func TestContextWithTraceParent(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	SetExporter(exporter)
+	defer SetExporter(nil)
+
+	for _, tc := range []struct {
+		traceparent string
+		remote      bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-00", true},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902-01", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"not a traceparent", false},
+		{"", false},
+	} {
+		exporter.Reset()
+		_, span := Start(ContextWithTraceParent(context.Background(), tc.traceparent), "login")
+		span.End()
+		data := exporter.Spans()[0]
+		remote := data.TraceID == "4bf92f3577b34da6a3ce929d0e0e4736" && data.ParentSpanID == "00f067aa0ba902b7"
+		if remote != tc.remote {
+			t.Fatalf("%q: expected remote parent %t, got %#v", tc.traceparent, tc.remote, data)
+		}
+		if !tc.remote && (data.ParentSpanID != "" || len(data.TraceID) != 32) {
+			t.Fatalf("%q: expected a new trace, got %#v", tc.traceparent, data)
+		}
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	SetExporter(nil)
+	ctx, span := Start(context.Background(), "login")
+	if span != nil || SpanFromContext(ctx) != nil {
+		t.Fatal("expected no span without an exporter")
+	}
+	// A nil span is safe to use.
+	span.SetAttribute("key", "value")
+	span.SetError(errors.New("boom"))
+	span.End()
+}
+
+func TestContextWithExporter(t *testing.T) {
+	global := NewInMemoryExporter()
+	SetExporter(global)
+	defer SetExporter(nil)
+
+	logs := &bytes.Buffer{}
+	ctx := ContextWithExporter(context.Background(), NewLogExporter(hclog.New(&hclog.LoggerOptions{Output: logs})))
+	ctx, root := Start(ctx, "login")
+	_, child := Start(ctx, "login.sts")
+	child.SetAttribute("region", "ap-guangzhou")
+	child.SetError(errors.New("boom"))
+	child.End()
+	root.End()
+
+	if len(global.Spans()) != 0 {
+		t.Fatal("expected no spans to reach the registered exporter")
+	}
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 logged spans, got %q", logs.String())
+	}
+	for _, want := range []string{"name=login.sts", "trace_id=" + root.TraceID(), "status=error", "error=boom",
+		"attribute.region=ap-guangzhou"} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("expected %q in %q", want, lines[0])
+		}
+	}
+}